@@ -7,19 +7,57 @@ import (
 	"github.com/mitchellh/colorstring"
 )
 
-var suggestions []string
+// Suggestion is a shell command that the user is advised to run. Safe suggestions may be executed
+// by gogit itself in fix mode, unsafe ones (pushing, deleting, anything with placeholders) are
+// only shown.
+type Suggestion struct {
+	Cmd  string
+	Safe bool
+}
+
+var suggestions []Suggestion
 
+// Suggest records an unsafe suggestion and returns it as a string.
 func Suggest(f string, args ...interface{}) string {
+	return add(false, f, args...)
+}
+
+// Fix records a safe suggestion and returns it as a string.
+func Fix(f string, args ...interface{}) string {
+	return add(true, f, args...)
+}
+
+func add(safe bool, f string, args ...interface{}) string {
 	s := fmt.Sprintf(f, args...)
-	suggestions = append(suggestions, "  "+s)
+	suggestions = append(suggestions, Suggestion{Cmd: s, Safe: safe})
 	return s
 }
 
+// Mark returns a position in the list of suggestions, to be used in Since and Rewind.
+func Mark() int {
+	return len(suggestions)
+}
+
+// Since returns the suggestions that were recorded after the mark.
+func Since(mark int) []Suggestion {
+	if mark >= len(suggestions) {
+		return nil
+	}
+	return suggestions[mark:]
+}
+
+// Rewind drops the suggestions that were recorded after the mark.
+func Rewind(mark int) {
+	if mark < len(suggestions) {
+		suggestions = suggestions[:mark]
+	}
+}
+
 func Output() {
 	if len(suggestions) > 0 {
 		colorstring.Fprintf(os.Stdout, "[gogit] [yellow]suggestion(s):\n")
 		for _, s := range suggestions {
-			colorstring.Fprintf(os.Stdout, "[yellow]%v\n", s)
+			colorstring.Fprintf(os.Stdout, "[yellow]  %v\n", s.Cmd)
 		}
 	}
 }
@@ -12,3 +12,32 @@ func TestSuggest(t *testing.T) {
 		t.Errorf("len(suggestions) = %v, want 10", slen)
 	}
 }
+
+func TestMarkSinceRewind(t *testing.T) {
+	suggestions = nil
+	Suggest("unsafe %v", 1)
+	mark := Mark()
+	if got := Fix("safe %v", 2); got != "safe 2" {
+		t.Errorf("Fix(%q,2) = %q, want %q", "safe %v", got, "safe 2")
+	}
+	Suggest("unsafe %v", 3)
+
+	since := Since(mark)
+	if len(since) != 2 {
+		t.Fatalf("Since(%v) = %v, want 2 suggestions", mark, since)
+	}
+	if !since[0].Safe || since[0].Cmd != "safe 2" {
+		t.Errorf("Since(%v)[0] = %+v, want safe suggestion %q", mark, since[0], "safe 2")
+	}
+	if since[1].Safe || since[1].Cmd != "unsafe 3" {
+		t.Errorf("Since(%v)[1] = %+v, want unsafe suggestion %q", mark, since[1], "unsafe 3")
+	}
+	if got := Since(Mark()); got != nil {
+		t.Errorf("Since(Mark()) = %v, want nil", got)
+	}
+
+	Rewind(mark)
+	if slen := len(suggestions); slen != 1 {
+		t.Errorf("after Rewind(%v): len(suggestions) = %v, want 1", mark, slen)
+	}
+}
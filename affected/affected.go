@@ -0,0 +1,88 @@
+// Package affected maps changed files to the Go packages of a module that they affect: the
+// packages that contain them, and the packages that import those, directly or indirectly.
+package affected
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// ListFormat is the -f template for `go list -e ./...` whose output ParseList reads: each
+// package, followed by its imports, including those of its tests.
+const ListFormat = `{{.ImportPath}}{{range .Imports}} {{.}}{{end}}` +
+	`{{range .TestImports}} {{.}}{{end}}{{range .XTestImports}} {{.}}{{end}}`
+
+// Graph holds the imports of each package of a module.
+type Graph map[string][]string
+
+// ParseList reads the output of `go list -e -f ListFormat ./...`.
+func ParseList(lines []string) Graph {
+	g := Graph{}
+	for _, l := range lines {
+		fields := strings.Fields(l)
+		if len(fields) == 0 {
+			continue
+		}
+		g[fields[0]] = append(g[fields[0]], fields[1:]...)
+	}
+	return g
+}
+
+// Packages returns the packages of the module modPath that contain the files, given relative to
+// the module directory. A file in a directory without a package (e.g. testdata) belongs to the
+// nearest package above it. All is true when a file concerns the whole module, e.g. go.mod.
+func (g Graph) Packages(modPath string, files []string) (pkgs []string, all bool) {
+	found := map[string]struct{}{}
+	for _, f := range files {
+		if f == "go.mod" || f == "go.sum" {
+			return nil, true
+		}
+		for dir := path.Dir(f); ; dir = path.Dir(dir) {
+			p := modPath
+			if dir != "." {
+				p = modPath + "/" + dir
+			}
+			if _, ok := g[p]; ok {
+				found[p] = struct{}{}
+				break
+			}
+			if dir == "." {
+				break
+			}
+		}
+	}
+	return sorted(found), false
+}
+
+// ReverseDeps returns the packages plus the packages of the graph that import them, directly or
+// indirectly, sorted.
+func (g Graph) ReverseDeps(pkgs []string) []string {
+	importers := map[string][]string{}
+	for p, imports := range g {
+		for _, imp := range imports {
+			importers[imp] = append(importers[imp], p)
+		}
+	}
+	found := map[string]struct{}{}
+	todo := append([]string{}, pkgs...)
+	for len(todo) > 0 {
+		p := todo[0]
+		todo = todo[1:]
+		if _, ok := found[p]; ok {
+			continue
+		}
+		found[p] = struct{}{}
+		todo = append(todo, importers[p]...)
+	}
+	return sorted(found)
+}
+
+func sorted(set map[string]struct{}) []string {
+	list := []string{}
+	for s := range set {
+		list = append(list, s)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,84 @@
+package affected
+
+import (
+	"reflect"
+	"testing"
+)
+
+var graph = ParseList([]string{
+	"example.com/m fmt example.com/m/a",
+	"example.com/m/a example.com/m/b testing",
+	"example.com/m/b strings",
+	"example.com/m/c example.com/m/b",
+	"example.com/m/d",
+	"example.com/m/e example.com/m/d",
+})
+
+func TestParseList(t *testing.T) {
+	want := []string{"example.com/m/b", "testing"}
+	if got := graph["example.com/m/a"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseList() imports of a = %v, want %v", got, want)
+	}
+	if got := graph["example.com/m/d"]; len(got) != 0 {
+		t.Errorf("ParseList() imports of d = %v, want none", got)
+	}
+}
+
+func TestPackages(t *testing.T) {
+	for _, test := range []struct {
+		files   []string
+		want    []string
+		wantAll bool
+	}{
+		{
+			files: []string{"main.go", "a/a.go", "a/a_test.go"},
+			want:  []string{"example.com/m", "example.com/m/a"},
+		},
+		{
+			files: []string{"b/testdata/in.txt", "README.md"},
+			want:  []string{"example.com/m", "example.com/m/b"},
+		},
+		{
+			files:   []string{"a/a.go", "go.sum"},
+			want:    nil,
+			wantAll: true,
+		},
+		{
+			files: []string{},
+			want:  []string{},
+		},
+	} {
+		got, all := graph.Packages("example.com/m", test.files)
+		if !reflect.DeepEqual(got, test.want) || all != test.wantAll {
+			t.Errorf("Packages(%v) = %v,%v, want %v,%v", test.files, got, all, test.want, test.wantAll)
+		}
+	}
+}
+
+func TestReverseDeps(t *testing.T) {
+	for _, test := range []struct {
+		pkgs []string
+		want []string
+	}{
+		{
+			pkgs: []string{"example.com/m/b"},
+			want: []string{"example.com/m", "example.com/m/a", "example.com/m/b", "example.com/m/c"},
+		},
+		{
+			pkgs: []string{"example.com/m/d"},
+			want: []string{"example.com/m/d", "example.com/m/e"},
+		},
+		{
+			pkgs: []string{"example.com/m"},
+			want: []string{"example.com/m"},
+		},
+		{
+			pkgs: nil,
+			want: []string{},
+		},
+	} {
+		if got := graph.ReverseDeps(test.pkgs); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ReverseDeps(%v) = %v, want %v", test.pkgs, got, test.want)
+		}
+	}
+}
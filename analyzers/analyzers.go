@@ -0,0 +1,162 @@
+// Package analyzers runs static analysis passes in-process using the golang.org/x/tools analysis
+// framework: the go vet suite, plus extras such as nilness, shadow and unusedwrite.
+package analyzers
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/KarelKubat/gogit/position"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/analysis/passes/nilness"
+	"golang.org/x/tools/go/analysis/passes/shadow"
+	"golang.org/x/tools/go/analysis/passes/unusedwrite"
+	"golang.org/x/tools/go/analysis/suite/vet"
+	"golang.org/x/tools/go/packages"
+)
+
+// TypeCheck is the analyzer name of diagnostics for packages that fail to load or type-check.
+const TypeCheck = "typecheck"
+
+// Analyzers beyond the go vet suite. The ones in offByDefault must be enabled explicitly.
+var (
+	extras       = []*analysis.Analyzer{nilness.Analyzer, shadow.Analyzer, unusedwrite.Analyzer}
+	offByDefault = map[string]bool{
+		shadow.Analyzer.Name: true, // reports many intentional shadowings, e.g. of err
+	}
+)
+
+// Diagnostic is a finding of an analyzer. The file is relative to the analyzed directory.
+type Diagnostic struct {
+	Analyzer string
+	File     string
+	Line     int
+	Column   int
+	Msg      string
+}
+
+// String returns the diagnostic as file:line:column: msg (analyzer).
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%v:%v:%v: %v (%v)", d.File, d.Line, d.Column, d.Msg, d.Analyzer)
+}
+
+// all returns the known analyzers by name.
+func all() map[string]*analysis.Analyzer {
+	m := map[string]*analysis.Analyzer{}
+	for _, list := range [][]*analysis.Analyzer{vet.Suite, extras} {
+		for _, a := range list {
+			m[a.Name] = a
+		}
+	}
+	return m
+}
+
+// Names returns the names of all known analyzers, sorted.
+func Names() []string {
+	var names []string
+	for n := range all() {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Defaults returns the names of the analyzers that run unless disabled, sorted.
+func Defaults() []string {
+	var names []string
+	for _, n := range Names() {
+		if !offByDefault[n] {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// Select returns the analyzers with the given names.
+func Select(names []string) ([]*analysis.Analyzer, error) {
+	known := all()
+	var as []*analysis.Analyzer
+	for _, n := range names {
+		a, ok := known[n]
+		if !ok {
+			return nil, fmt.Errorf("unknown analyzer %q", n)
+		}
+		as = append(as, a)
+	}
+	return as, nil
+}
+
+// Run loads the packages matching the patterns (e.g. "./...") in dir, tests included, and runs the
+// analyzers on them. Packages that fail to load or type-check are reported as TypeCheck
+// diagnostics and not analyzed.
+func Run(dir string, patterns []string, as []*analysis.Analyzer) ([]Diagnostic, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := packages.Load(&packages.Config{
+		Mode:  packages.LoadAllSyntax,
+		Dir:   abs,
+		Tests: true,
+	}, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[Diagnostic]struct{}{}
+	var diags []Diagnostic
+	add := func(d Diagnostic) {
+		if rel, err := filepath.Rel(abs, d.File); err == nil {
+			d.File = filepath.ToSlash(rel)
+		}
+		if _, ok := seen[d]; ok {
+			return // e.g. a package and its test variant
+		}
+		seen[d] = struct{}{}
+		diags = append(diags, d)
+	}
+	var ok []*packages.Package
+	for _, p := range pkgs {
+		if len(p.Errors) == 0 {
+			ok = append(ok, p)
+			continue
+		}
+		for _, e := range p.Errors {
+			file, line, col := position.Split(e.Pos)
+			add(Diagnostic{Analyzer: TypeCheck, File: file, Line: line, Column: col, Msg: e.Msg})
+		}
+	}
+	if len(ok) > 0 && len(as) > 0 {
+		graph, err := checker.Analyze(as, ok, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, act := range graph.Roots {
+			if act.Err != nil {
+				return nil, fmt.Errorf("%v: %v", act, act.Err)
+			}
+			for _, d := range act.Diagnostics {
+				pos := act.Package.Fset.Position(d.Pos)
+				add(Diagnostic{
+					Analyzer: act.Analyzer.Name,
+					File:     pos.Filename,
+					Line:     pos.Line,
+					Column:   pos.Column,
+					Msg:      d.Message,
+				})
+			}
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i], diags[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return diags, nil
+}
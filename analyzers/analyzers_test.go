@@ -0,0 +1,109 @@
+package analyzers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDefaults(t *testing.T) {
+	defaults := map[string]bool{}
+	for _, n := range Defaults() {
+		defaults[n] = true
+	}
+	for _, test := range []struct {
+		name string
+		want bool
+	}{
+		{name: "printf", want: true},
+		{name: "nilness", want: true},
+		{name: "unusedwrite", want: true},
+		{name: "shadow", want: false},
+	} {
+		if got := defaults[test.name]; got != test.want {
+			t.Errorf("Defaults() has %q = %v, want %v", test.name, got, test.want)
+		}
+	}
+	if len(Names()) <= len(Defaults()) {
+		t.Errorf("Names() = %v, want more than Defaults() = %v", Names(), Defaults())
+	}
+}
+
+func TestSelect(t *testing.T) {
+	as, err := Select([]string{"printf", "shadow"})
+	if err != nil || len(as) != 2 || as[0].Name != "printf" || as[1].Name != "shadow" {
+		t.Errorf("Select(printf, shadow) = %v,%v, want printf and shadow", as, err)
+	}
+	if _, err := Select([]string{"nosuch"}); err == nil {
+		t.Errorf("Select(nosuch) = _,nil, want error")
+	}
+}
+
+func TestRun(t *testing.T) {
+	for _, test := range []struct {
+		names []string
+		src   string
+		want  []Diagnostic
+	}{
+		{
+			names: Defaults(),
+			src:   "package x\n\nimport \"fmt\"\n\nfunc F() {\n\tfmt.Printf(\"%d\\n\", \"s\")\n}\n",
+			want: []Diagnostic{{
+				Analyzer: "printf",
+				File:     "x.go",
+				Line:     6,
+				Column:   14,
+				Msg:      "fmt.Printf format %d has arg \"s\" of wrong type string",
+			}},
+		},
+		{
+			names: Defaults(),
+			src:   "package x\n\nfunc F(p *int) int {\n\tif p == nil {\n\t\treturn *p\n\t}\n\treturn 0\n}\n",
+			want: []Diagnostic{{
+				Analyzer: "nilness",
+				File:     "x.go",
+				Line:     5,
+				Column:   10,
+				Msg:      "nil dereference in load",
+			}},
+		},
+		{
+			names: []string{"printf"},
+			src:   "package x\n\nfunc F() int {\n\treturn \"s\"\n}\n",
+			want: []Diagnostic{{
+				Analyzer: TypeCheck,
+				File:     "x.go",
+				Line:     4,
+				Column:   9,
+				Msg:      "cannot use \"s\" (untyped string constant) as int value in return statement",
+			}},
+		},
+		{
+			names: Defaults(),
+			src:   "package x\n\nfunc F() int {\n\treturn 1\n}\n",
+			want:  nil,
+		},
+	} {
+		dir := t.TempDir()
+		for fname, content := range map[string]string{
+			"go.mod": "module example.com/x\n\ngo 1.22\n",
+			"x.go":   test.src,
+		} {
+			if err := os.WriteFile(filepath.Join(dir, fname), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		as, err := Select(test.names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Run(dir, []string{"./..."}, as)
+		if err != nil {
+			t.Fatalf("Run(%q) = _,%v, want nil error", test.src, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Run(%q) = %+v, want %+v", test.src, got, test.want)
+		}
+	}
+}
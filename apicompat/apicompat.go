@@ -0,0 +1,357 @@
+// Package apicompat compares the exported API of the packages of a module between two versions,
+// e.g. the highest remote tag and HEAD. Removing or changing exported identifiers is a breaking
+// change, which requires a major version bump; adding identifiers is not.
+package apicompat
+
+import (
+	"fmt"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Change is a difference in the exported API.
+type Change struct {
+	Package  string // relative to the module, "." for the top level
+	Name     string // ex. "New", "Tag.Less", "Tag.Major"
+	Msg      string // ex. "removed", or "changed from func() to func(int)"
+	Breaking bool
+	File     string // location in the new version, if any
+	Line     int
+}
+
+func (c Change) String() string {
+	if c.Package == "." {
+		return fmt.Sprintf("%v %v", c.Name, c.Msg)
+	}
+	return fmt.Sprintf("%v: %v %v", c.Package, c.Name, c.Msg)
+}
+
+// API is the exported API of a module: its non-internal, non-main packages by relative path.
+type API struct {
+	dir      string
+	modPath  string
+	pkgs     map[string]*packages.Package
+	packages map[string]*types.Package
+}
+
+// Load type-checks the packages of the module in dir.
+func Load(dir string) (*API, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedModule | packages.NeedImports,
+		Dir:  dir,
+	}
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return nil, fmt.Errorf("can't load the packages in %v: %v", dir, err)
+	}
+	api := &API{
+		dir:      dir,
+		pkgs:     map[string]*packages.Package{},
+		packages: map[string]*types.Package{},
+	}
+	var problems []string
+	for _, p := range pkgs {
+		for _, e := range p.Errors {
+			problems = append(problems, e.Error())
+		}
+		if p.Module == nil || p.Name == "main" || p.Types == nil || isInternal(p.PkgPath) {
+			continue
+		}
+		api.modPath = p.Module.Path
+		rel := relative(p.PkgPath, p.Module.Path)
+		api.pkgs[rel] = p
+		api.packages[rel] = p.Types
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("can't load the packages in %v: %v", dir, strings.Join(problems, "; "))
+	}
+	return api, nil
+}
+
+func isInternal(path string) bool {
+	for _, part := range strings.Split(path, "/") {
+		if part == "internal" {
+			return true
+		}
+	}
+	return false
+}
+
+func relative(path, modPath string) string {
+	if path == modPath {
+		return "."
+	}
+	return strings.TrimPrefix(path, modPath+"/")
+}
+
+// Compare returns the differences between the old and the new API, sorted by package and name.
+func Compare(old, new *API) []Change {
+	var changes []Change
+	for _, rel := range sortedKeys(old.packages) {
+		np, ok := new.packages[rel]
+		if !ok {
+			changes = append(changes, Change{Package: rel, Name: "package", Msg: "removed", Breaking: true})
+			continue
+		}
+		cmp := &comparer{old: old, new: new, rel: rel}
+		cmp.compareScopes(old.packages[rel], np)
+		changes = append(changes, cmp.changes...)
+	}
+	for _, rel := range sortedKeys(new.packages) {
+		if _, ok := old.packages[rel]; !ok {
+			changes = append(changes, Change{Package: rel, Name: "package", Msg: "added"})
+		}
+	}
+	return changes
+}
+
+// Breaking returns the breaking changes.
+func Breaking(changes []Change) []Change {
+	var breaking []Change
+	for _, c := range changes {
+		if c.Breaking {
+			breaking = append(breaking, c)
+		}
+	}
+	return breaking
+}
+
+type comparer struct {
+	old, new *API
+	rel      string
+	changes  []Change
+}
+
+func (c *comparer) add(obj types.Object, name, msg string, breaking bool) {
+	ch := Change{Package: c.rel, Name: name, Msg: msg, Breaking: breaking}
+	if obj != nil {
+		if p, ok := c.new.pkgs[c.rel]; ok && p.Fset != nil && obj.Pos().IsValid() {
+			pos := p.Fset.Position(obj.Pos())
+			ch.File = pos.Filename
+			if rel, err := filepath.Rel(c.new.dir, pos.Filename); err == nil {
+				ch.File = rel
+			}
+			ch.Line = pos.Line
+		}
+	}
+	c.changes = append(c.changes, ch)
+}
+
+func (c *comparer) compareScopes(op, np *types.Package) {
+	oldScope, newScope := op.Scope(), np.Scope()
+	for _, name := range oldScope.Names() {
+		o := oldScope.Lookup(name)
+		if !o.Exported() {
+			continue
+		}
+		n := newScope.Lookup(name)
+		if n == nil || !n.Exported() {
+			c.add(nil, name, "removed", true)
+			continue
+		}
+		c.compareObjects(name, o, n)
+	}
+	for _, name := range newScope.Names() {
+		n := newScope.Lookup(name)
+		if n.Exported() && oldScope.Lookup(name) == nil {
+			c.add(n, name, "added", false)
+		}
+	}
+}
+
+func kind(obj types.Object) string {
+	switch obj.(type) {
+	case *types.Const:
+		return "const"
+	case *types.Var:
+		return "var"
+	case *types.Func:
+		return "func"
+	case *types.TypeName:
+		return "type"
+	default:
+		return "object"
+	}
+}
+
+func (c *comparer) compareObjects(name string, o, n types.Object) {
+	if kind(o) != kind(n) {
+		c.add(n, name, fmt.Sprintf("changed from %v to %v", kind(o), kind(n)), true)
+		return
+	}
+	ot, nt := c.typeString(o.Type(), c.old), c.typeString(n.Type(), c.new)
+	switch o := o.(type) {
+	case *types.TypeName:
+		c.compareTypes(name, o, n.(*types.TypeName))
+	default:
+		if ot != nt {
+			c.add(n, name, fmt.Sprintf("changed from %v to %v", ot, nt), true)
+		}
+	}
+}
+
+// compareTypes compares named types: their underlying types, and their exported fields and methods.
+func (c *comparer) compareTypes(name string, o, n *types.TypeName) {
+	ou, nu := o.Type().Underlying(), n.Type().Underlying()
+	ost, oIsStruct := ou.(*types.Struct)
+	nst, nIsStruct := nu.(*types.Struct)
+	switch {
+	case oIsStruct && nIsStruct:
+		c.compareFields(name, ost, nst)
+	default:
+		if ot, nt := c.typeString(ou, c.old), c.typeString(nu, c.new); ot != nt {
+			c.add(n, name, fmt.Sprintf("changed from %v to %v", ot, nt), true)
+		}
+	}
+	if _, isInterface := ou.(*types.Interface); isInterface {
+		return // methods are part of the underlying type
+	}
+	oms, nms := methods(o.Type()), methods(n.Type())
+	for _, mname := range sortedKeys(oms) {
+		om := oms[mname]
+		nm, ok := nms[mname]
+		if !ok {
+			c.add(nil, name+"."+mname, "removed", true)
+			continue
+		}
+		if ot, nt := c.typeString(om.Type(), c.old), c.typeString(nm.Type(), c.new); ot != nt {
+			c.add(nm, name+"."+mname, fmt.Sprintf("changed from %v to %v", ot, nt), true)
+		}
+	}
+	for _, mname := range sortedKeys(nms) {
+		if _, ok := oms[mname]; !ok {
+			c.add(nms[mname], name+"."+mname, "added", false)
+		}
+	}
+}
+
+// compareFields compares the exported fields of structs. Adding fields is not a breaking change.
+func (c *comparer) compareFields(name string, ost, nst *types.Struct) {
+	nfields := map[string]*types.Var{}
+	for i := 0; i < nst.NumFields(); i++ {
+		if f := nst.Field(i); f.Exported() {
+			nfields[f.Name()] = f
+		}
+	}
+	ofields := map[string]bool{}
+	for i := 0; i < ost.NumFields(); i++ {
+		of := ost.Field(i)
+		if !of.Exported() {
+			continue
+		}
+		ofields[of.Name()] = true
+		nf, ok := nfields[of.Name()]
+		if !ok {
+			c.add(nil, name+"."+of.Name(), "removed", true)
+			continue
+		}
+		if ot, nt := c.typeString(of.Type(), c.old), c.typeString(nf.Type(), c.new); ot != nt {
+			c.add(nf, name+"."+of.Name(), fmt.Sprintf("changed from %v to %v", ot, nt), true)
+		}
+	}
+	for _, fname := range sortedKeys(nfields) {
+		if !ofields[fname] {
+			c.add(nfields[fname], name+"."+fname, "added", false)
+		}
+	}
+}
+
+// methods returns the exported methods of a named type, including those with pointer receivers.
+func methods(t types.Type) map[string]*types.Func {
+	ms := map[string]*types.Func{}
+	if _, isInterface := t.Underlying().(*types.Interface); isInterface {
+		return ms
+	}
+	set := types.NewMethodSet(types.NewPointer(t))
+	for i := 0; i < set.Len(); i++ {
+		if f, ok := set.At(i).Obj().(*types.Func); ok && f.Exported() {
+			ms[f.Name()] = f
+		}
+	}
+	return ms
+}
+
+// typeString writes types of the module relative to the module path, so that they compare equal
+// when the module path changes, e.g. to a /v2 suffix. Parameter names are left out, renaming them
+// isn't a change.
+func (c *comparer) typeString(t types.Type, api *API) string {
+	return types.TypeString(unnamed(t), func(p *types.Package) string {
+		if p.Path() == api.modPath || strings.HasPrefix(p.Path(), api.modPath+"/") {
+			if rel := relative(p.Path(), api.modPath); rel != c.rel {
+				return rel
+			}
+			return ""
+		}
+		return p.Path()
+	})
+}
+
+// unnamed returns the type with the names of function parameters and results removed. Generic
+// functions are returned as-is, as their type parameters can't be reused.
+func unnamed(t types.Type) types.Type {
+	switch t := t.(type) {
+	case *types.Signature:
+		if t.TypeParams().Len() > 0 {
+			return t
+		}
+		return types.NewSignatureType(nil, nil, nil, unnamedTuple(t.Params()), unnamedTuple(t.Results()), t.Variadic())
+	case *types.Pointer:
+		return types.NewPointer(unnamed(t.Elem()))
+	case *types.Slice:
+		return types.NewSlice(unnamed(t.Elem()))
+	case *types.Array:
+		return types.NewArray(unnamed(t.Elem()), t.Len())
+	case *types.Map:
+		return types.NewMap(unnamed(t.Key()), unnamed(t.Elem()))
+	case *types.Chan:
+		return types.NewChan(t.Dir(), unnamed(t.Elem()))
+	case *types.Struct:
+		fields := make([]*types.Var, t.NumFields())
+		tags := make([]string, t.NumFields())
+		for i := range fields {
+			f := t.Field(i)
+			fields[i] = types.NewField(f.Pos(), f.Pkg(), f.Name(), unnamed(f.Type()), f.Embedded())
+			tags[i] = t.Tag(i)
+		}
+		return types.NewStruct(fields, tags)
+	case *types.Interface:
+		methods := make([]*types.Func, t.NumExplicitMethods())
+		for i := range methods {
+			m := t.ExplicitMethod(i)
+			methods[i] = types.NewFunc(m.Pos(), m.Pkg(), m.Name(), unnamed(m.Type()).(*types.Signature))
+		}
+		embeddeds := make([]types.Type, t.NumEmbeddeds())
+		for i := range embeddeds {
+			embeddeds[i] = t.EmbeddedType(i)
+		}
+		return types.NewInterfaceType(methods, embeddeds).Complete()
+	default:
+		return t
+	}
+}
+
+func unnamedTuple(tuple *types.Tuple) *types.Tuple {
+	vars := make([]*types.Var, tuple.Len())
+	for i := range vars {
+		v := tuple.At(i)
+		vars[i] = types.NewParam(v.Pos(), v.Pkg(), "", unnamed(v.Type()))
+	}
+	return types.NewTuple(vars...)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,132 @@
+package apicompat
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeModule(t *testing.T, modPath string, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module " + modPath + "\n\ngo 1.20\n"
+	for name, content := range files {
+		fname := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fname, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCompare(t *testing.T) {
+	oldDir := writeModule(t, "example.com/m", map[string]string{
+		"m.go": `package m
+
+import "example.com/m/sub"
+
+const Version = "1"
+
+var Default = 1
+
+type T struct {
+	A int
+	B string
+	s int
+}
+
+func (t *T) Get() int   { return t.A }
+func (t T) Name() string { return t.B }
+
+type I interface{ Do() }
+
+func New(a int) *T        { return &T{A: a} }
+func Gone()               {}
+func UseSub() sub.S        { return sub.S{} }
+`,
+		"sub/sub.go":             "package sub\n\ntype S struct{}\n",
+		"removed/removed.go":     "package removed\n\nfunc F() {}\n",
+		"internal/in/in.go":      "package in\n\nfunc F() {}\n",
+		"cmd/tool/main.go":       "package main\n\nfunc main() {}\n",
+		"unchanged/unchanged.go": "package unchanged\n\nfunc F() {}\n",
+	})
+	newDir := writeModule(t, "example.com/m/v2", map[string]string{
+		"m.go": `package m
+
+import "example.com/m/v2/sub"
+
+const Version = "2"
+
+var Default = "1"
+
+type T struct {
+	A int
+	C bool
+}
+
+func (t *T) Get() (a int) { return t.A }
+func (t *T) Set(a int)   { t.A = a }
+
+type I interface {
+	Do()
+	Undo()
+}
+
+func New(a int, b string) *T { return &T{A: a} }
+func UseSub() sub.S          { return sub.S{} }
+func Added()                 {}
+`,
+		"sub/sub.go":             "package sub\n\ntype S struct{}\n",
+		"internal/in/in.go":      "package in\n\nfunc G() {}\n",
+		"unchanged/unchanged.go": "package unchanged\n\nfunc F() {}\n",
+		"added/added.go":         "package added\n\nfunc F() {}\n",
+	})
+	oldAPI, err := Load(oldDir)
+	if err != nil {
+		t.Fatalf("Load(old) = _,%v", err)
+	}
+	newAPI, err := Load(newDir)
+	if err != nil {
+		t.Fatalf("Load(new) = _,%v", err)
+	}
+
+	var got []string
+	for _, c := range Compare(oldAPI, newAPI) {
+		mark := "+"
+		if c.Breaking {
+			mark = "!"
+		}
+		got = append(got, mark+" "+c.String())
+	}
+	want := []string{
+		"! Default changed from int to string",
+		"! Gone removed",
+		"! I changed from interface{Do()} to interface{Do(); Undo()}",
+		"! New changed from func(int) *T to func(int, string) *T",
+		"! T.B removed",
+		"+ T.C added",
+		"! T.Name removed",
+		"+ T.Set added",
+		"+ Added added",
+		"! removed: package removed",
+		"+ added: package added",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Compare() =\n%q\nwant\n%q", got, want)
+	}
+
+	breaking := Breaking(Compare(oldAPI, newAPI))
+	if len(breaking) != 7 {
+		t.Errorf("Breaking() = %v, want 7 changes", breaking)
+	}
+	if c := breaking[0]; c.File != "m.go" || c.Line != 7 {
+		t.Errorf("Breaking()[0] at %v:%v, want m.go:7", c.File, c.Line)
+	}
+	if changes := Compare(oldAPI, oldAPI); len(changes) != 0 {
+		t.Errorf("Compare(old, old) = %v, want no changes", changes)
+	}
+}
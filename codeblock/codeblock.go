@@ -0,0 +1,284 @@
+// Package codeblock type-checks the Go code blocks of Markdown documents. Fragments without a
+// package clause are wrapped into a scratch package inside the module, with the imports that they
+// need, so that they are checked with go/types against the packages of the module.
+package codeblock
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KarelKubat/gogit/markdown"
+	"github.com/KarelKubat/gogit/position"
+	"github.com/KarelKubat/gogit/snippet"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/packages"
+)
+
+// NoCompile in the info string of a code block, e.g. "```go nocompile", skips the block.
+const NoCompile = "nocompile"
+
+// Kind is how a code block is turned into a Go file.
+type Kind int
+
+const (
+	File  Kind = iota // has a package clause, checked as is
+	Decls             // declarations, wrapped into a package
+	Stmts             // statements, wrapped into a function
+)
+
+// Block is a Go code block of a Markdown document.
+type Block struct {
+	Line   int      // 1-based line of the first line of code
+	Indent int      // spaces that were removed from the lines, for fences in e.g. list items
+	Code   []string // the lines between the fences
+}
+
+// Blocks returns the closed ```go code blocks of a document, except for the blocks that are marked
+// NoCompile and the blocks below embed directives, which hold code from sources that are compiled
+// anyway.
+func Blocks(content string) []Block {
+	embedded := map[int]bool{}
+	for _, d := range snippet.Directives(content) {
+		if d.Fence != nil {
+			embedded[d.Fence.Start] = true
+		}
+	}
+	var blocks []Block
+	for _, f := range markdown.Fences(content) {
+		if f.End == 0 || embedded[f.Start] || (f.Lang() != "go" && f.Lang() != "golang") ||
+			slices.Contains(strings.Fields(f.Info), NoCompile) {
+			continue
+		}
+		b := Block{Line: f.Start + 1, Indent: f.Indent}
+		for _, l := range f.Lines {
+			// CommonMark removes the indentation of the opening fence from the contents.
+			n := min(f.Indent, len(l)-len(strings.TrimLeft(l, " ")))
+			b.Code = append(b.Code, l[n:])
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// Source returns block b of document doc as a Go file, and how it was wrapped. A //line directive
+// maps the positions in the file to the lines of the document.
+func Source(doc string, b Block) (string, Kind) {
+	code := strings.Join(b.Code, "\n")
+	directive := fmt.Sprintf("//line %v:%v:1\n", path.Base(doc), b.Line)
+	if _, err := parser.ParseFile(token.NewFileSet(), "", code, parser.PackageClauseOnly); err == nil {
+		return directive + code + "\n", File
+	}
+	src := "package scratch\n\n" + directive + code + "\n"
+	if _, err := parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution); err == nil {
+		return src, Decls
+	}
+	return "package scratch\n\nfunc _() {\n" + directive + code + "\n}\n", Stmts
+}
+
+// Diagnostic is a problem in a code block.
+type Diagnostic struct {
+	Line   int // 1-based line in the document
+	Column int // 1-based, 0 when unknown
+	Msg    string
+}
+
+// Check type-checks the code blocks of document doc, against the packages of the module in modDir
+// and its dependencies. Each block is a package of its own in a scratch directory in the module,
+// which is removed afterwards.
+func Check(modDir, doc string, blocks []Block) ([]Diagnostic, error) {
+	abs, err := filepath.Abs(modDir)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(abs, "_gogit-codeblocks-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	pkgNames, err := candidates(abs)
+	if err != nil {
+		return nil, err
+	}
+	kinds := make([]Kind, len(blocks))
+	var patterns []string
+	for i, b := range blocks {
+		dir := filepath.Join(tmp, fmt.Sprintf("b%d", i))
+		if err := os.Mkdir(dir, 0755); err != nil {
+			return nil, err
+		}
+		src, kind := Source(doc, b)
+		if kind != File {
+			src = addImports(src, pkgNames)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "block.go"), []byte(src), 0644); err != nil {
+			return nil, err
+		}
+		kinds[i] = kind
+		patterns = append(patterns, "./"+filepath.ToSlash(filepath.Join(filepath.Base(tmp), filepath.Base(dir))))
+	}
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.LoadAllSyntax,
+		Dir:  abs,
+	}, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	var diags []Diagnostic
+	for _, p := range pkgs {
+		i, err := strconv.Atoi(strings.TrimPrefix(path.Base(p.PkgPath), "b"))
+		if err != nil || i < 0 || i >= len(blocks) {
+			return nil, fmt.Errorf("unexpected package %v while checking code blocks", p.PkgPath)
+		}
+		errs := p.Errors
+		if parseErrs := slices.DeleteFunc(slices.Clone(errs), func(e packages.Error) bool {
+			return e.Kind != packages.ParseError
+		}); len(parseErrs) > 0 {
+			errs = parseErrs // type errors of code that doesn't parse are noise
+		}
+		for _, e := range errs {
+			// Statements are only a fragment, the variables that they declare are used further on.
+			if kinds[i] == Stmts && strings.Contains(e.Msg, "declared and not used") {
+				continue
+			}
+			d := Diagnostic{Line: blocks[i].Line, Msg: e.Msg}
+			if _, line, col := position.Split(e.Pos); line > 0 {
+				d.Line = line
+				if col > 0 {
+					d.Column = col + blocks[i].Indent
+				}
+			}
+			diags = append(diags, d)
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].Line != diags[j].Line {
+			return diags[i].Line < diags[j].Line
+		}
+		return diags[i].Column < diags[j].Column
+	})
+	return diags, nil
+}
+
+// candidates returns the packages that fragments may use without importing them, by package
+// name: the packages of the standard library, and the packages of the module in dir and of its
+// dependencies. When names collide, the module's own packages win, then the standard library,
+// then the shortest import path.
+func candidates(dir string) (map[string]string, error) {
+	b, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	modPath := modfile.ModulePath(b)
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName, Dir: dir}, "std", "all")
+	if err != nil {
+		return nil, err
+	}
+	rank := func(p string) int {
+		switch {
+		case p == modPath || strings.HasPrefix(p, modPath+"/"):
+			return 0
+		case !strings.Contains(strings.Split(p, "/")[0], "."):
+			return 1
+		}
+		return 2
+	}
+	byName := map[string]string{}
+	for _, p := range pkgs {
+		if p.Name == "" || p.Name == "main" || strings.HasPrefix(p.PkgPath, "vendor/") ||
+			slices.Contains(strings.Split(p.PkgPath, "/"), "internal") {
+			continue
+		}
+		have, ok := byName[p.Name]
+		if !ok || rank(p.PkgPath) < rank(have) ||
+			(rank(p.PkgPath) == rank(have) && (len(p.PkgPath) < len(have) ||
+				(len(p.PkgPath) == len(have) && p.PkgPath < have))) {
+			byName[p.Name] = p.PkgPath
+		}
+	}
+	return byName, nil
+}
+
+// addImports adds imports to a wrapped fragment for the package names that it uses as qualifiers
+// (e.g. strings in strings.Split) but doesn't declare or import, using pkgNames to find their
+// paths. The imports go between the package clause and the //line directive, so that positions
+// still map to the document.
+func addImports(src string, pkgNames map[string]string) string {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return src
+	}
+	declared := map[string]bool{}
+	for _, imp := range f.Imports {
+		name := path.Base(strings.Trim(imp.Path.Value, `"`))
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		declared[name] = true
+	}
+	var qualifiers []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				declareIdents(declared, n.Lhs...)
+			}
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				declareIdents(declared, n.Key, n.Value)
+			}
+		case *ast.ValueSpec:
+			declareIdents(declared, identExprs(n.Names)...)
+		case *ast.Field:
+			declareIdents(declared, identExprs(n.Names)...)
+		case *ast.FuncDecl:
+			declared[n.Name.Name] = true
+		case *ast.TypeSpec:
+			declared[n.Name.Name] = true
+		case *ast.SelectorExpr:
+			if id, ok := n.X.(*ast.Ident); ok {
+				qualifiers = append(qualifiers, id.Name)
+			}
+		}
+		return true
+	})
+	var missing []string
+	for _, q := range qualifiers {
+		if p, ok := pkgNames[q]; ok && !declared[q] {
+			declared[q] = true
+			missing = append(missing, fmt.Sprintf("\t%q\n", p))
+		}
+	}
+	if len(missing) == 0 {
+		return src
+	}
+	sort.Strings(missing)
+	pkgClause, rest, _ := strings.Cut(src, "\n")
+	return pkgClause + "\n\nimport (\n" + strings.Join(missing, "") + ")\n" + rest
+}
+
+func declareIdents(declared map[string]bool, es ...ast.Expr) {
+	for _, e := range es {
+		if id, ok := e.(*ast.Ident); ok {
+			declared[id.Name] = true
+		}
+	}
+}
+
+func identExprs(ids []*ast.Ident) []ast.Expr {
+	var es []ast.Expr
+	for _, id := range ids {
+		es = append(es, id)
+	}
+	return es
+}
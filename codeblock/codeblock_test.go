@@ -0,0 +1,166 @@
+package codeblock
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBlocks(t *testing.T) {
+	content := strings.Join([]string{
+		"# Doc",                  // 1
+		"```go",                  // 2
+		"x := 1",                 // 3
+		"```",                    // 4
+		"```go nocompile",        // 5
+		"broken(",                // 6
+		"```",                    // 7
+		"<!-- embed: x.go F -->", // 8
+		"```go",                  // 9
+		"func F() {}",            // 10
+		"```",                    // 11
+		"```sh",                  // 12
+		"go test",                // 13
+		"```",                    // 14
+		"- item",                 // 15
+		"  ~~~golang",            // 16
+		"  y := 2",               // 17
+		" z := 3",                // 18
+		"  ~~~",                  // 19
+		"```go",                  // 20
+		"unclosed",               // 21
+	}, "\n")
+	want := []Block{
+		{Line: 3, Code: []string{"x := 1"}},
+		{Line: 17, Indent: 2, Code: []string{"y := 2", "z := 3"}},
+	}
+	if got := Blocks(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("Blocks() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSource(t *testing.T) {
+	for _, test := range []struct {
+		code     []string
+		wantSrc  string
+		wantKind Kind
+	}{
+		{
+			code:     []string{"package main", "", "func main() {}"},
+			wantSrc:  "//line README.md:7:1\npackage main\n\nfunc main() {}\n",
+			wantKind: File,
+		},
+		{
+			code:     []string{"// Package x.", "package x"},
+			wantSrc:  "//line README.md:7:1\n// Package x.\npackage x\n",
+			wantKind: File,
+		},
+		{
+			code:     []string{"func F() int {", "    return 1", "}"},
+			wantSrc:  "package scratch\n\n//line README.md:7:1\nfunc F() int {\n    return 1\n}\n",
+			wantKind: Decls,
+		},
+		{
+			code:     []string{"x := F()", "fmt.Println(x)"},
+			wantSrc:  "package scratch\n\nfunc _() {\n//line README.md:7:1\nx := F()\nfmt.Println(x)\n}\n",
+			wantKind: Stmts,
+		},
+	} {
+		src, kind := Source("docs/README.md", Block{Line: 7, Code: test.code})
+		if src != test.wantSrc || kind != test.wantKind {
+			t.Errorf("Source(_,%q) = %q,%v, want %q,%v", test.code, src, kind, test.wantSrc, test.wantKind)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	for fname, content := range map[string]string{
+		"go.mod":         "module example.com/m\n\ngo 1.22\n",
+		"greet/greet.go": "package greet\n\n// Hello returns a greeting.\nfunc Hello(name string) string {\n\treturn \"hello \" + name\n}\n",
+	} {
+		fname = filepath.Join(dir, fname)
+		if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fname, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, test := range []struct {
+		blocks []Block
+		want   []Diagnostic
+	}{
+		{
+			blocks: []Block{
+				{Line: 3, Code: []string{"s := greet.Hello(\"you\")", "fmt.Println(strings.ToUpper(s))"}},
+				{Line: 9, Code: []string{"func F() string {", "    return greet.Hello(\"F\")", "}"}},
+				{Line: 20, Code: []string{"package main", "", "import \"example.com/m/greet\"", "",
+					"func main() {", "    println(greet.Hello(\"main\"))", "}"}},
+				{Line: 30, Code: []string{"unused := 1"}},
+			},
+			want: nil,
+		},
+		{
+			blocks: []Block{
+				{Line: 3, Code: []string{"s := greet.Hello(1)"}},
+				{Line: 9, Code: []string{"func F() int {", "    return greet.Goodbye()", "}"}},
+				{Line: 20, Indent: 2, Code: []string{"x := undefinedThing"}},
+				{Line: 30, Code: []string{"if x {"}},
+			},
+			want: []Diagnostic{
+				{Line: 3, Column: 18, Msg: `cannot use 1 (untyped int constant) as string value in argument to greet.Hello`},
+				{Line: 10, Column: 18, Msg: "undefined: greet.Goodbye"},
+				{Line: 20, Column: 8, Msg: "undefined: undefinedThing"},
+				{Line: 31, Column: 3, Msg: "expected ';', found 'EOF'"},
+				{Line: 31, Column: 3, Msg: "expected '}', found 'EOF'"},
+			},
+		},
+	} {
+		got, err := Check(dir, "README.md", test.blocks)
+		if err != nil {
+			t.Fatalf("Check(%+v) = _,%v, want nil error", test.blocks, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Check(%+v) = %+v, want %+v", test.blocks, got, test.want)
+		}
+		if leftover, _ := filepath.Glob(filepath.Join(dir, "_gogit-*")); len(leftover) > 0 {
+			t.Errorf("Check() left %v behind", leftover)
+		}
+	}
+}
+
+func TestAddImports(t *testing.T) {
+	pkgNames := map[string]string{"fmt": "fmt", "strings": "strings", "greet": "example.com/m/greet"}
+	for _, test := range []struct {
+		src  string
+		want string
+	}{
+		{
+			src:  "package scratch\n\n//line README.md:3:1\nvar x = 1\n",
+			want: "package scratch\n\n//line README.md:3:1\nvar x = 1\n",
+		},
+		{
+			src: "package scratch\n\nfunc _() {\n//line README.md:3:1\nfmt.Println(greet.Hello(strings.ToUpper(\"x\")))\n}\n",
+			want: "package scratch\n\nimport (\n\t\"example.com/m/greet\"\n\t\"fmt\"\n\t\"strings\"\n)\n\n" +
+				"func _() {\n//line README.md:3:1\nfmt.Println(greet.Hello(strings.ToUpper(\"x\")))\n}\n",
+		},
+		{
+			// Declared names and imported packages are no qualifiers to import.
+			src: "package scratch\n\nimport f \"fmt\"\n\nfunc F(strings []string) {\n\tgreet := struct{ fmt int }{}\n" +
+				"\tf.Println(strings, greet.fmt)\n}\n",
+			want: "package scratch\n\nimport f \"fmt\"\n\nfunc F(strings []string) {\n\tgreet := struct{ fmt int }{}\n" +
+				"\tf.Println(strings, greet.fmt)\n}\n",
+		},
+		{
+			src:  "package scratch\n\nfunc _() {\nunknown.F(\n}\n",
+			want: "package scratch\n\nfunc _() {\nunknown.F(\n}\n",
+		},
+	} {
+		if got := addImports(test.src, pkgNames); got != test.want {
+			t.Errorf("addImports(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// Package config reads the per-repository gogit configuration from .gogit.toml at the top level
+// of a git repository. When the file is absent, the defaults reproduce gogit's built-in behavior.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// Name of the configuration file, expected at the git top level.
+	FileName = ".gogit.toml"
+
+	// Default readme to be checked and manipulated.
+	DefaultReadme = "README.md"
+
+	// How the version bump that Conventional Commit messages imply is used.
+	ConventionalOff     = "off"     // ignored
+	ConventionalSuggest = "suggest" // suggested, an under-bumped tag is a warning
+	ConventionalEnforce = "enforce" // an under-bumped tag is an error, the default
+)
+
+// Default supported remote repositories.
+var DefaultRemoteRepos = []string{"github.com", "gitlab.com"}
+
+// Default languages of code blocks whose tabs are kept, as they are significant.
+var DefaultKeepTabs = []string{"make", "makefile", "tsv"}
+
+// TOC configures the table of contents of the readme.
+type TOC struct {
+	MinLevel  int  `toml:"min-level"`  // headings from this level on are listed, default 2
+	MaxLevel  int  `toml:"max-level"`  // headings up to this level are listed, default 6
+	CheckOnly bool `toml:"check-only"` // fail when the TOC is stale, rather than refreshing it
+}
+
+// Links configures the check of links in Markdown files.
+type Links struct {
+	ReportExternal bool `toml:"report-external"` // list external URLs, without fetching them
+}
+
+// Untab configures the untabbing of code blocks in Markdown files.
+type Untab struct {
+	KeepTabs []string `toml:"keep-tabs"` // languages of code blocks that keep their tabs, e.g. "make"
+}
+
+// Checks enables or disables named checks for one phase (e.g. "pre-commit").
+type Checks struct {
+	Enable  []string `toml:"enable"`
+	Disable []string `toml:"disable"`
+}
+
+type Config struct {
+	Readme        string            `toml:"readme"`
+	RequiredFiles []string          `toml:"required-files"`
+	RemoteRepos   []string          `toml:"remote-repos"`
+	Conventional  string            `toml:"conventional-commits"`
+	StagedOnly    bool              `toml:"staged-only"`
+	Analyzers     Checks            `toml:"analyzers"`
+	TOC           TOC               `toml:"toc"`
+	Links         Links             `toml:"links"`
+	Untab         Untab             `toml:"untab"`
+	Checks        map[string]Checks `toml:"checks"`
+}
+
+// New returns a configuration holding the defaults.
+func New() *Config {
+	c := &Config{}
+	c.fillDefaults()
+	return c
+}
+
+// Load reads the configuration file. A missing file is not an error, the defaults are returned.
+func Load(fname string) (*Config, error) {
+	if _, err := os.Stat(fname); errors.Is(err, os.ErrNotExist) {
+		return New(), nil
+	}
+	c := &Config{}
+	md, err := toml.DecodeFile(fname, c)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", fname, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := []string{}
+		for _, k := range undecoded {
+			keys = append(keys, k.String())
+		}
+		return nil, fmt.Errorf("%v: unknown key(s): %v", fname, strings.Join(keys, ", "))
+	}
+	c.fillDefaults()
+	return c, nil
+}
+
+func (c *Config) fillDefaults() {
+	if c.Readme == "" {
+		c.Readme = DefaultReadme
+	}
+	if c.RequiredFiles == nil {
+		c.RequiredFiles = []string{c.Readme, "LICENSE.md", ".gitignore", "go.mod"}
+	}
+	if c.RemoteRepos == nil {
+		c.RemoteRepos = DefaultRemoteRepos
+	}
+	if c.Conventional == "" {
+		c.Conventional = ConventionalEnforce
+	}
+	if c.TOC.MinLevel == 0 {
+		c.TOC.MinLevel = 2
+	}
+	if c.TOC.MaxLevel == 0 {
+		c.TOC.MaxLevel = 6
+	}
+	if c.Untab.KeepTabs == nil {
+		c.Untab.KeepTabs = DefaultKeepTabs
+	}
+	if c.Checks == nil {
+		c.Checks = map[string]Checks{}
+	}
+}
+
+// Validate verifies that all configured phases, check names and analyzer names are known, so that
+// typos don't silently disable checks, and that settings have supported values.
+func (c *Config) Validate(phases map[string][]string, checks, analyzers []string) error {
+	known := map[string]struct{}{}
+	for _, ch := range checks {
+		known[ch] = struct{}{}
+	}
+	knownAnalyzers := map[string]struct{}{}
+	for _, a := range analyzers {
+		knownAnalyzers[a] = struct{}{}
+	}
+	var problems []string
+	if c.TOC.MinLevel < 1 || c.TOC.MaxLevel > 6 || c.TOC.MinLevel > c.TOC.MaxLevel {
+		problems = append(problems, fmt.Sprintf("toc levels must satisfy 1 <= min-level <= max-level <= 6, not %v and %v",
+			c.TOC.MinLevel, c.TOC.MaxLevel))
+	}
+	for _, list := range [][]string{c.Analyzers.Enable, c.Analyzers.Disable} {
+		for _, a := range list {
+			if _, ok := knownAnalyzers[a]; !ok {
+				problems = append(problems, fmt.Sprintf("unknown analyzer %q in [analyzers]", a))
+			}
+		}
+	}
+	switch c.Conventional {
+	case ConventionalOff, ConventionalSuggest, ConventionalEnforce:
+	default:
+		problems = append(problems, fmt.Sprintf("conventional-commits must be %q, %q or %q, not %q",
+			ConventionalOff, ConventionalSuggest, ConventionalEnforce, c.Conventional))
+	}
+	for _, phase := range sortedKeys(c.Checks) {
+		if _, ok := phases[phase]; !ok {
+			problems = append(problems, fmt.Sprintf("unknown phase %q in [checks.%v]", phase, phase))
+			continue
+		}
+		for _, list := range [][]string{c.Checks[phase].Enable, c.Checks[phase].Disable} {
+			for _, ch := range list {
+				if _, ok := known[ch]; !ok {
+					problems = append(problems, fmt.Sprintf("unknown check %q in [checks.%v]", ch, phase))
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%v: %v", FileName, strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// For returns the names of the checks to run for a phase: the defaults, minus what's disabled,
+// plus what's enabled and not yet present.
+func (c *Config) For(phase string, defaults []string) []string {
+	return merge(defaults, c.Checks[phase])
+}
+
+// AnalyzersFor returns the names of the analyzers to run: the defaults, minus what's disabled,
+// plus what's enabled and not yet present.
+func (c *Config) AnalyzersFor(defaults []string) []string {
+	return merge(defaults, c.Analyzers)
+}
+
+func merge(defaults []string, cc Checks) []string {
+	disabled := map[string]struct{}{}
+	for _, ch := range cc.Disable {
+		disabled[ch] = struct{}{}
+	}
+	present := map[string]struct{}{}
+	names := []string{}
+	for _, list := range [][]string{defaults, cc.Enable} {
+		for _, ch := range list {
+			if _, ok := disabled[ch]; ok {
+				continue
+			}
+			if _, ok := present[ch]; ok {
+				continue
+			}
+			present[ch] = struct{}{}
+			names = append(names, ch)
+		}
+	}
+	return names
+}
+
+func sortedKeys(m map[string]Checks) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,256 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	for _, test := range []struct {
+		content           string
+		wantErr           string
+		wantReadme        string
+		wantRequiredFiles []string
+		wantRemoteRepos   []string
+		wantStagedOnly    bool
+		wantKeepTabs      []string
+		wantConventional  string
+	}{
+		{
+			content:           "",
+			wantErr:           "",
+			wantReadme:        "README.md",
+			wantRequiredFiles: []string{"README.md", "LICENSE.md", ".gitignore", "go.mod"},
+			wantRemoteRepos:   []string{"github.com", "gitlab.com"},
+			wantKeepTabs:      []string{"make", "makefile", "tsv"},
+			wantConventional:  "enforce",
+		},
+		{
+			content:           `conventional-commits = "suggest"`,
+			wantErr:           "",
+			wantReadme:        "README.md",
+			wantRequiredFiles: []string{"README.md", "LICENSE.md", ".gitignore", "go.mod"},
+			wantRemoteRepos:   []string{"github.com", "gitlab.com"},
+			wantConventional:  "suggest",
+		},
+		{
+			content:           `staged-only = true`,
+			wantErr:           "",
+			wantReadme:        "README.md",
+			wantRequiredFiles: []string{"README.md", "LICENSE.md", ".gitignore", "go.mod"},
+			wantRemoteRepos:   []string{"github.com", "gitlab.com"},
+			wantStagedOnly:    true,
+		},
+		{
+			content:           `readme = "README.markdown"`,
+			wantErr:           "",
+			wantReadme:        "README.markdown",
+			wantRequiredFiles: []string{"README.markdown", "LICENSE.md", ".gitignore", "go.mod"},
+			wantRemoteRepos:   []string{"github.com", "gitlab.com"},
+		},
+		{
+			content: `
+required-files = ["go.mod"]
+remote-repos = ["example.com"]
+[analyzers]
+enable = ["shadow"]
+[links]
+report-external = true
+[untab]
+keep-tabs = ["make", "go"]
+[checks.pre-commit]
+disable = ["mdtoc"]
+`,
+			wantErr:           "",
+			wantReadme:        "README.md",
+			wantRequiredFiles: []string{"go.mod"},
+			wantRemoteRepos:   []string{"example.com"},
+			wantKeepTabs:      []string{"make", "go"},
+		},
+		{
+			content: "readme = 'x.md'\nreadmee = 'y.md'",
+			wantErr: "unknown key(s): readmee",
+		},
+		{
+			content: "[checks.pre-commit]\ndisabled = ['mdtoc']",
+			wantErr: "unknown key(s): checks.pre-commit.disabled",
+		},
+		{
+			content: "readme = [",
+			wantErr: FileName,
+		},
+	} {
+		fname := filepath.Join(t.TempDir(), FileName)
+		if err := os.WriteFile(fname, []byte(test.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		c, err := Load(fname)
+		switch {
+		case err == nil && test.wantErr != "":
+			t.Errorf("Load(%q) = _,nil, want error with %q", test.content, test.wantErr)
+			continue
+		case err != nil && test.wantErr == "":
+			t.Errorf("Load(%q) = _,%q, want nil error", test.content, err.Error())
+			continue
+		case err != nil && !strings.Contains(err.Error(), test.wantErr):
+			t.Errorf("Load(%q) = _,%q, want error with %q", test.content, err.Error(), test.wantErr)
+			continue
+		case err != nil:
+			continue
+		}
+		if c.Readme != test.wantReadme {
+			t.Errorf("Load(%q).Readme = %q, want %q", test.content, c.Readme, test.wantReadme)
+		}
+		if !reflect.DeepEqual(c.RequiredFiles, test.wantRequiredFiles) {
+			t.Errorf("Load(%q).RequiredFiles = %v, want %v", test.content, c.RequiredFiles, test.wantRequiredFiles)
+		}
+		if !reflect.DeepEqual(c.RemoteRepos, test.wantRemoteRepos) {
+			t.Errorf("Load(%q).RemoteRepos = %v, want %v", test.content, c.RemoteRepos, test.wantRemoteRepos)
+		}
+		if c.TOC.MinLevel != 2 || c.TOC.MaxLevel != 6 {
+			t.Errorf("Load(%q).TOC = %+v, want levels 2 to 6", test.content, c.TOC)
+		}
+		if test.wantKeepTabs != nil && !reflect.DeepEqual(c.Untab.KeepTabs, test.wantKeepTabs) {
+			t.Errorf("Load(%q).Untab.KeepTabs = %v, want %v", test.content, c.Untab.KeepTabs, test.wantKeepTabs)
+		}
+		if test.wantConventional != "" && c.Conventional != test.wantConventional {
+			t.Errorf("Load(%q).Conventional = %q, want %q", test.content, c.Conventional, test.wantConventional)
+		}
+		if c.StagedOnly != test.wantStagedOnly {
+			t.Errorf("Load(%q).StagedOnly = %v, want %v", test.content, c.StagedOnly, test.wantStagedOnly)
+		}
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), FileName))
+	if err != nil {
+		t.Fatalf("Load(missing) = _,%q, want nil error", err.Error())
+	}
+	if c.Readme != DefaultReadme {
+		t.Errorf("Load(missing).Readme = %q, want %q", c.Readme, DefaultReadme)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	phases := map[string][]string{"pre-commit": {"a", "b"}}
+	checks := []string{"a", "b", "c"}
+	analyzers := []string{"printf", "shadow"}
+	for _, test := range []struct {
+		checks       map[string]Checks
+		conventional string
+		analyzers    Checks
+		toc          *TOC
+		wantErr      string
+	}{
+		{
+			checks:  map[string]Checks{"pre-commit": {Enable: []string{"c"}, Disable: []string{"a"}}},
+			wantErr: "",
+		},
+		{
+			checks:  map[string]Checks{"pre-comit": {}},
+			wantErr: `unknown phase "pre-comit"`,
+		},
+		{
+			checks:  map[string]Checks{"pre-commit": {Disable: []string{"d"}}},
+			wantErr: `unknown check "d"`,
+		},
+		{
+			conventional: "enforce",
+			wantErr:      "",
+		},
+		{
+			conventional: "always",
+			wantErr:      `conventional-commits must be "off", "suggest" or "enforce", not "always"`,
+		},
+		{
+			analyzers: Checks{Enable: []string{"shadow"}, Disable: []string{"printf"}},
+			wantErr:   "",
+		},
+		{
+			analyzers: Checks{Enable: []string{"shadows"}},
+			wantErr:   `unknown analyzer "shadows" in [analyzers]`,
+		},
+		{
+			toc:     &TOC{MinLevel: 1, MaxLevel: 3},
+			wantErr: "",
+		},
+		{
+			toc:     &TOC{MinLevel: 3, MaxLevel: 2},
+			wantErr: "toc levels must satisfy 1 <= min-level <= max-level <= 6, not 3 and 2",
+		},
+		{
+			toc:     &TOC{MinLevel: 2, MaxLevel: 7},
+			wantErr: "toc levels",
+		},
+	} {
+		c := New()
+		c.Checks = test.checks
+		c.Analyzers = test.analyzers
+		if test.toc != nil {
+			c.TOC = *test.toc
+		}
+		if test.conventional != "" {
+			c.Conventional = test.conventional
+		}
+		err := c.Validate(phases, checks, analyzers)
+		switch {
+		case err == nil && test.wantErr != "":
+			t.Errorf("%+v .Validate() = nil, want error with %q", test.checks, test.wantErr)
+		case err != nil && test.wantErr == "":
+			t.Errorf("%+v .Validate() = %q, want nil error", test.checks, err.Error())
+		case err != nil && !strings.Contains(err.Error(), test.wantErr):
+			t.Errorf("%+v .Validate() = %q, want error with %q", test.checks, err.Error(), test.wantErr)
+		}
+	}
+}
+
+func TestFor(t *testing.T) {
+	for _, test := range []struct {
+		checks   Checks
+		defaults []string
+		want     []string
+	}{
+		{
+			checks:   Checks{},
+			defaults: []string{"a", "b"},
+			want:     []string{"a", "b"},
+		},
+		{
+			checks:   Checks{Disable: []string{"a"}},
+			defaults: []string{"a", "b"},
+			want:     []string{"b"},
+		},
+		{
+			checks:   Checks{Enable: []string{"c", "a"}},
+			defaults: []string{"a", "b"},
+			want:     []string{"a", "b", "c"},
+		},
+		{
+			checks:   Checks{Enable: []string{"c"}, Disable: []string{"c"}},
+			defaults: []string{"a"},
+			want:     []string{"a"},
+		},
+	} {
+		c := New()
+		c.Checks["pre-commit"] = test.checks
+		if got := c.For("pre-commit", test.defaults); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%+v .For(_,%v) = %v, want %v", test.checks, test.defaults, got, test.want)
+		}
+	}
+}
+
+func TestAnalyzersFor(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Analyzers = Checks{Enable: []string{"shadow"}, Disable: []string{"printf"}}
+	want := []string{"nilness", "shadow"}
+	if got := c.AnalyzersFor([]string{"nilness", "printf"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("AnalyzersFor() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,121 @@
+// Package conventional classifies commit messages following Conventional Commits
+// (https://www.conventionalcommits.org) into the version bump that they imply: `feat:` is a minor
+// bump, `fix:` a patch, and `BREAKING CHANGE:` or a `!` after the type/scope a major bump.
+package conventional
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/KarelKubat/gogit/tag"
+)
+
+// LogFormat is the `git log --format` that ParseLog expects: each message is preceded by a record
+// separator, so that messages can be told apart.
+const LogFormat = "--format=%x1e%B"
+
+const recordSeparator = "\x1e"
+
+// Bump is the part of a version that needs increasing.
+type Bump int
+
+const (
+	None Bump = iota
+	Patch
+	Minor
+	Major
+)
+
+func (b Bump) String() string {
+	switch b {
+	case Patch:
+		return "patch"
+	case Minor:
+		return "minor"
+	case Major:
+		return "major"
+	default:
+		return "none"
+	}
+}
+
+var (
+	// ex. "feat(parser)!: drop support for v1 files"
+	headerRe = regexp.MustCompile(`^(\w+)(?:\([^)]*\))?(!)?: \S`)
+
+	// footer, ex. "BREAKING CHANGE: the config file moved"
+	breakingRe = regexp.MustCompile(`^BREAKING[ -]CHANGE: `)
+)
+
+// Classify returns the bump that one commit message implies. Messages that don't follow
+// Conventional Commits, and types other than feat and fix (e.g. docs or chore) imply None.
+func Classify(msg string) Bump {
+	lines := strings.Split(strings.TrimSpace(msg), "\n")
+	for _, l := range lines[1:] {
+		if breakingRe.MatchString(strings.TrimSpace(l)) {
+			return Major
+		}
+	}
+	m := headerRe.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	switch {
+	case m == nil:
+		return None
+	case m[2] == "!":
+		return Major
+	case strings.ToLower(m[1]) == "feat":
+		return Minor
+	case strings.ToLower(m[1]) == "fix":
+		return Patch
+	default:
+		return None
+	}
+}
+
+// Implied returns the highest bump that the messages imply.
+func Implied(msgs []string) Bump {
+	b := None
+	for _, msg := range msgs {
+		if c := Classify(msg); c > b {
+			b = c
+		}
+	}
+	return b
+}
+
+// ParseLog splits the output lines of `git log` using LogFormat into commit messages.
+func ParseLog(lines []string) []string {
+	var msgs []string
+	for _, l := range lines {
+		if strings.HasPrefix(l, recordSeparator) {
+			msgs = append(msgs, strings.TrimPrefix(l, recordSeparator))
+			continue
+		}
+		if len(msgs) > 0 {
+			msgs[len(msgs)-1] += "\n" + l
+		}
+	}
+	return msgs
+}
+
+// Next returns the lowest tag after tg that satisfies the bump. Before v1.0.0 anything may change,
+// so a major bump is a minor one. None still means a patch, any next tag should be higher.
+func Next(tg *tag.Tag, b Bump) *tag.Tag {
+	if b == Major && tg.Major == 0 {
+		b = Minor
+	}
+	switch b {
+	case Major:
+		return tg.NextMajor()
+	case Minor:
+		return tg.NextMinor()
+	default:
+		return tg.NextPatch()
+	}
+}
+
+// Satisfies is true when tg, a candidate next version, is at least the required one. A prerelease
+// counts as its release, so v2.0.0-rc.1 satisfies v2.0.0.
+func Satisfies(tg, required *tag.Tag) bool {
+	release := &tag.Tag{Major: tg.Major, Minor: tg.Minor, Detail: tg.Detail}
+	return !release.Less(required)
+}
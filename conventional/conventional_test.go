@@ -0,0 +1,172 @@
+package conventional
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/KarelKubat/gogit/tag"
+)
+
+func TestClassify(t *testing.T) {
+	for _, test := range []struct {
+		msg  string
+		want Bump
+	}{
+		{
+			msg:  "feat: add bump command",
+			want: Minor,
+		},
+		{
+			msg:  "feat(tag): support prereleases",
+			want: Minor,
+		},
+		{
+			msg:  "fix: don't suggest deleting rc tags",
+			want: Patch,
+		},
+		{
+			msg:  "fix(hooks)!: rename marker",
+			want: Major,
+		},
+		{
+			msg:  "refactor!: drop the errs slice",
+			want: Major,
+		},
+		{
+			msg:  "chore: update deps\n\nBREAKING CHANGE: needs go 1.22",
+			want: Major,
+		},
+		{
+			msg:  "feat: x\n\nBREAKING-CHANGE: y",
+			want: Major,
+		},
+		{
+			msg:  "docs: explain configuration",
+			want: None,
+		},
+		{
+			msg:  "Update README.md",
+			want: None,
+		},
+		{
+			msg:  "feat:missing space",
+			want: None,
+		},
+		{
+			msg:  "",
+			want: None,
+		},
+	} {
+		if got := Classify(test.msg); got != test.want {
+			t.Errorf("Classify(%q) = %v, want %v", test.msg, got, test.want)
+		}
+	}
+}
+
+func TestImplied(t *testing.T) {
+	for _, test := range []struct {
+		msgs []string
+		want Bump
+	}{
+		{
+			msgs: nil,
+			want: None,
+		},
+		{
+			msgs: []string{"docs: x", "fix: y"},
+			want: Patch,
+		},
+		{
+			msgs: []string{"fix: y", "feat: z", "chore: w"},
+			want: Minor,
+		},
+		{
+			msgs: []string{"feat: z", "feat!: w"},
+			want: Major,
+		},
+	} {
+		if got := Implied(test.msgs); got != test.want {
+			t.Errorf("Implied(%q) = %v, want %v", test.msgs, got, test.want)
+		}
+	}
+}
+
+func TestParseLog(t *testing.T) {
+	lines := []string{
+		"\x1efeat: a",
+		"body of a",
+		"\x1efix: b",
+		"\x1echore: c",
+		"BREAKING CHANGE: d",
+	}
+	want := []string{
+		"feat: a\nbody of a",
+		"fix: b",
+		"chore: c\nBREAKING CHANGE: d",
+	}
+	if got := ParseLog(lines); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLog(%q) = %q, want %q", lines, got, want)
+	}
+}
+
+func TestNextSatisfies(t *testing.T) {
+	for _, test := range []struct {
+		tag       string
+		bump      Bump
+		wantNext  string
+		candidate string
+		wantOK    bool
+	}{
+		{
+			tag:       "v1.2.3",
+			bump:      None,
+			wantNext:  "v1.2.4",
+			candidate: "v1.2.4",
+			wantOK:    true,
+		},
+		{
+			tag:       "v1.2.3",
+			bump:      Minor,
+			wantNext:  "v1.3.0",
+			candidate: "v1.2.4",
+			wantOK:    false,
+		},
+		{
+			tag:       "v1.2.3",
+			bump:      Major,
+			wantNext:  "v2.0.0",
+			candidate: "v2.0.0-rc.1",
+			wantOK:    true,
+		},
+		{
+			tag:       "v1.2.3",
+			bump:      Patch,
+			wantNext:  "v1.2.4",
+			candidate: "v2.0.0",
+			wantOK:    true,
+		},
+		{
+			tag:       "v0.4.1",
+			bump:      Major,
+			wantNext:  "v0.5.0",
+			candidate: "v0.5.0",
+			wantOK:    true,
+		},
+	} {
+		tg, err := tag.New(test.tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := Next(tg, test.bump)
+		if got := next.String(); got != test.wantNext {
+			t.Errorf("Next(%v, %v) = %v, want %v", tg, test.bump, got, test.wantNext)
+		}
+		cand, err := tag.New(test.candidate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Satisfies(cand, next); got != test.wantOK {
+			t.Errorf("Satisfies(%v, %v) = %v, want %v", cand, next, got, test.wantOK)
+		}
+	}
+}
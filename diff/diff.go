@@ -0,0 +1,184 @@
+// Package diff computes line-based unified diffs, as shown by `diff -u`.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Lines of unchanged context around each change.
+	context = 3
+
+	// MaxEdits is the number of inserted plus deleted lines beyond which no diff is computed: the
+	// trace of the edits takes memory in the square of their number.
+	MaxEdits = 2000
+)
+
+// op is one step of an edit script: a line that is kept (' '), deleted ('-') or inserted ('+').
+type op struct {
+	kind byte
+	line string
+}
+
+// Unified returns the unified diff between old and new, named oldName and newName in the
+// header; or nil when they are equal. When they differ in more than MaxEdits lines, the diff is
+// just a line that says so.
+func Unified(oldName, newName, old, new string) []string {
+	if old == new {
+		return nil
+	}
+	ops, ok := edits(split(old), split(new))
+	if !ok {
+		return []string{fmt.Sprintf("%v and %v differ in more than %v lines, not showing the diff", oldName, newName, MaxEdits)}
+	}
+	out := []string{"--- " + oldName, "+++ " + newName}
+	for _, h := range hunks(ops) {
+		out = append(out, header(ops, h[0], h[1]))
+		for _, o := range ops[h[0]:h[1]] {
+			out = append(out, string(o.kind)+o.line)
+		}
+	}
+	return out
+}
+
+// split returns the lines of a text, without a trailing empty line.
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// edits returns the shortest edit script from a to b, using Myers' algorithm, or false when it
+// takes more than MaxEdits insertions and deletions.
+func edits(a, b []string) ([]op, bool) {
+	n, m := len(a), len(b)
+	max := n + m
+	v := make([]int, 2*max+2)
+	// trace[d] holds the furthest x of the diagonals -d..d before step d, which are the only
+	// ones that step d reads, so that the trace grows with the edits rather than with the input.
+	var trace [][]int
+	for d := 0; d <= max && d <= MaxEdits; d++ {
+		trace = append(trace, append([]int{}, v[max-d:max+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = v[max+k+1] // down: insertion
+			} else {
+				x = v[max+k-1] + 1 // right: deletion
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[max+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// backtrack walks the trace of edits back from the end, and returns the edit script.
+func backtrack(a, b []string, trace [][]int) []op {
+	var ops []op
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d] // diagonal k is at v[d+k]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = v[d+prevK]
+		}
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, op{' ', a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, op{'+', b[y-1]})
+			} else {
+				ops = append(ops, op{'-', a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// hunks returns the [start,end) ranges of ops that form hunks: changes with their context, where
+// changes that are close together share a hunk.
+func hunks(ops []op) [][2]int {
+	var hs [][2]int
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == ' ' {
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i + 1
+		for j := i + 1; j < len(ops) && j <= end+2*context; j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			}
+		}
+		i = end - 1
+		end += context
+		if end > len(ops) {
+			end = len(ops)
+		}
+		hs = append(hs, [2]int{start, end})
+	}
+	return hs
+}
+
+// header returns the "@@ -l,s +l,s @@" line of the hunk ops[start:end].
+func header(ops []op, start, end int) string {
+	oldStart, newStart := 0, 0
+	for _, o := range ops[:start] {
+		if o.kind != '+' {
+			oldStart++
+		}
+		if o.kind != '-' {
+			newStart++
+		}
+	}
+	oldLen, newLen := 0, 0
+	for _, o := range ops[start:end] {
+		if o.kind != '+' {
+			oldLen++
+		}
+		if o.kind != '-' {
+			newLen++
+		}
+	}
+	return fmt.Sprintf("@@ -%v +%v @@", span(oldStart, oldLen), span(newStart, newLen))
+}
+
+// span formats the start line and length of one side of a hunk, like diff -u does.
+func span(before, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%v,0", before)
+	case 1:
+		return fmt.Sprintf("%v", before+1)
+	default:
+		return fmt.Sprintf("%v,%v", before+1, length)
+	}
+}
@@ -0,0 +1,89 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	for _, test := range []struct {
+		old, new string
+		want     []string
+	}{
+		{
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: nil,
+		},
+		{
+			old:  "a\nb\nc\n",
+			new:  "a\nB\nc\n",
+			want: []string{"--- old", "+++ new", "@@ -1,3 +1,3 @@", " a", "-b", "+B", " c"},
+		},
+		{
+			old:  "",
+			new:  "a\n",
+			want: []string{"--- old", "+++ new", "@@ -0,0 +1 @@", "+a"},
+		},
+		{
+			old:  "a\n",
+			new:  "",
+			want: []string{"--- old", "+++ new", "@@ -1 +0,0 @@", "-a"},
+		},
+		{
+			// Changes far apart are separate hunks, with 3 lines of context.
+			old: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new: "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			want: []string{
+				"--- old", "+++ new",
+				"@@ -1,3 +1,4 @@", "+0", " 1", " 2", " 3",
+				"@@ -9,4 +10,3 @@", " 9", " 10", " 11", "-12",
+			},
+		},
+		{
+			// Changes close together share a hunk.
+			old: "1\n2\n3\n4\n5\n6\n7\n8\n",
+			new: "1\nX\n3\n4\n5\n6\n7\nY\n",
+			want: []string{
+				"--- old", "+++ new",
+				"@@ -1,8 +1,8 @@", " 1", "-2", "+X", " 3", " 4", " 5", " 6", " 7", "-8", "+Y",
+			},
+		},
+		{
+			old: "func f() {\n\treturn  1\n}\n",
+			new: "func f() {\n\treturn 1\n}\n",
+			want: []string{
+				"--- old", "+++ new",
+				"@@ -1,3 +1,3 @@", " func f() {", "-\treturn  1", "+\treturn 1", " }",
+			},
+		},
+	} {
+		if got := Unified("old", "new", test.old, test.new); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Unified(_,_,%q,%q) =\n%v\nwant\n%v", test.old, test.new,
+				strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+		}
+	}
+}
+
+func TestUnifiedLarge(t *testing.T) {
+	var old, retabbed, changed []string
+	for i := range 5000 {
+		old = append(old, fmt.Sprintf("\tline %d", i))
+		retabbed = append(retabbed, fmt.Sprintf("    line %d", i))
+		changed = append(changed, fmt.Sprintf("\tline %d", i))
+	}
+	changed[2500] = "changed"
+	join := func(ls []string) string { return strings.Join(ls, "\n") + "\n" }
+
+	want := []string{"--- old", "+++ new", "@@ -2498,7 +2498,7 @@",
+		" \tline 2497", " \tline 2498", " \tline 2499", "-\tline 2500", "+changed", " \tline 2501", " \tline 2502", " \tline 2503"}
+	if got := Unified("old", "new", join(old), join(changed)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unified() of one changed line out of 5000 =\n%v\nwant\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	want = []string{"old and new differ in more than 2000 lines, not showing the diff"}
+	if got := Unified("old", "new", join(old), join(retabbed)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unified() of 5000 changed lines = %q, want %q", got, want)
+	}
+}
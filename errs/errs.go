@@ -1,24 +1,164 @@
+// Package errs collects the findings of a check: issues with a severity, an optional location
+// and suggestions to resolve them. Each check gets its own collection, so findings don't bleed
+// from one check into the next.
 package errs
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 )
 
-var errs []string
+type Severity int
 
-func Add(strs ...string) error {
-	for _, s := range strs {
+const (
+	Info Severity = iota
+	Warn
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warn:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// Finding is one issue reported by a check.
+type Finding struct {
+	Check       string
+	Severity    Severity
+	File        string // optional
+	Line        int    // optional, 0 when unknown
+	Column      int    // optional, 0 when unknown
+	Msg         string
+	Suggestions []string
+}
+
+// At sets the location of the finding and returns it, for chaining.
+func (f *Finding) At(file string, line int) *Finding {
+	f.File = file
+	f.Line = line
+	return f
+}
+
+// WithColumn sets the column of the finding and returns it, for chaining.
+func (f *Finding) WithColumn(column int) *Finding {
+	f.Column = column
+	return f
+}
+
+// Location returns file:line:column, file:line, or just the file, depending on what's known;
+// or "".
+func (f *Finding) Location() string {
+	switch {
+	case f.File == "":
+		return ""
+	case f.Line == 0:
+		return f.File
+	case f.Column == 0:
+		return fmt.Sprintf("%v:%v", f.File, f.Line)
+	default:
+		return fmt.Sprintf("%v:%v:%v", f.File, f.Line, f.Column)
+	}
+}
+
+func (f *Finding) String() string {
+	if loc := f.Location(); loc != "" {
+		return loc + ": " + f.Msg
+	}
+	return f.Msg
+}
+
+// Findings collects the findings of one check.
+type Findings struct {
+	Check string
+	List  []*Finding
+}
+
+func New(check string) *Findings {
+	return &Findings{
+		Check: check,
+	}
+}
+
+// Add records a finding. Suggestions are typically the return values of action.Suggest or
+// action.Fix.
+func (fs *Findings) Add(sev Severity, msg string, suggestions ...string) *Finding {
+	f := &Finding{
+		Check:    fs.Check,
+		Severity: sev,
+		Msg:      msg,
+	}
+	for _, s := range suggestions {
 		if s != "" {
-			errs = append(errs, s)
+			f.Suggestions = append(f.Suggestions, s)
 		}
 	}
-	return Err()
+	fs.List = append(fs.List, f)
+	return f
+}
+
+func (fs *Findings) Info(msg string, suggestions ...string) *Finding {
+	return fs.Add(Info, msg, suggestions...)
+}
+
+func (fs *Findings) Warn(msg string, suggestions ...string) *Finding {
+	return fs.Add(Warn, msg, suggestions...)
 }
 
-func Err() error {
-	if len(errs) == 0 {
+func (fs *Findings) Error(msg string, suggestions ...string) *Finding {
+	return fs.Add(Error, msg, suggestions...)
+}
+
+// Count returns the number of findings with the given severity.
+func (fs *Findings) Count(sev Severity) int {
+	n := 0
+	for _, f := range fs.List {
+		if f.Severity == sev {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed is true when there is at least one finding with severity Error.
+func (fs *Findings) Failed() bool {
+	return fs.Count(Error) > 0
+}
+
+// Err returns the findings with severity Error as one error, or nil.
+func (fs *Findings) Err() error {
+	var msgs []string
+	for _, f := range fs.List {
+		if f.Severity == Error {
+			msgs = append(msgs, f.String())
+		}
+	}
+	if len(msgs) == 0 {
 		return nil
 	}
-	return errors.New(strings.Join(errs, "\n"))
+	return errors.New(strings.Join(msgs, "\n"))
+}
+
+// Summary returns a short description, e.g. "ok", or "1 error, 2 warnings".
+func (fs *Findings) Summary() string {
+	var parts []string
+	for _, sev := range []Severity{Error, Warn} {
+		switch n := fs.Count(sev); n {
+		case 0:
+		case 1:
+			parts = append(parts, fmt.Sprintf("1 %v", sev))
+		default:
+			parts = append(parts, fmt.Sprintf("%v %vs", n, sev))
+		}
+	}
+	if len(parts) == 0 {
+		return "ok"
+	}
+	return strings.Join(parts, ", ")
 }
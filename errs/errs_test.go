@@ -4,6 +4,74 @@ import (
 	"testing"
 )
 
-func TestAll(t *testing.T) {
-	// TODO: add tests
+func TestLocation(t *testing.T) {
+	for _, test := range []struct {
+		file       string
+		line       int
+		column     int
+		wantString string
+	}{
+		{
+			file:       "",
+			line:       0,
+			wantString: "msg",
+		},
+		{
+			file:       "a.go",
+			line:       0,
+			wantString: "a.go: msg",
+		},
+		{
+			file:       "a.go",
+			line:       12,
+			wantString: "a.go:12: msg",
+		},
+		{
+			file:       "a.go",
+			line:       12,
+			column:     3,
+			wantString: "a.go:12:3: msg",
+		},
+	} {
+		f := New("check").Error("msg").At(test.file, test.line).WithColumn(test.column)
+		if got := f.String(); got != test.wantString {
+			t.Errorf("At(%q,%v).WithColumn(%v).String() = %q, want %q", test.file, test.line, test.column, got, test.wantString)
+		}
+	}
+}
+
+func TestFindings(t *testing.T) {
+	fs := New("check")
+	if fs.Failed() || fs.Err() != nil || fs.Summary() != "ok" {
+		t.Errorf("empty findings: Failed()=%v, Err()=%v, Summary()=%q, want false, nil, ok",
+			fs.Failed(), fs.Err(), fs.Summary())
+	}
+
+	fs.Info("info")
+	fs.Warn("warning", "suggestion", "")
+	if fs.Failed() || fs.Err() != nil {
+		t.Errorf("findings without errors: Failed()=%v, Err()=%v, want false and nil", fs.Failed(), fs.Err())
+	}
+	if got, want := fs.Summary(), "1 warning"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	if got := fs.List[1].Suggestions; len(got) != 1 || got[0] != "suggestion" {
+		t.Errorf("Suggestions = %v, want [suggestion]", got)
+	}
+	if got := fs.List[1].Check; got != "check" {
+		t.Errorf("Check = %q, want %q", got, "check")
+	}
+
+	fs.Error("first")
+	fs.Error("second").At("f.go", 3)
+	fs.Warn("another warning")
+	if !fs.Failed() {
+		t.Errorf("Failed() = false, want true")
+	}
+	if got, want := fs.Err().Error(), "first\nf.go:3: second"; got != want {
+		t.Errorf("Err() = %q, want %q", got, want)
+	}
+	if got, want := fs.Summary(), "2 errors, 2 warnings"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
 }
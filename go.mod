@@ -1,5 +1,12 @@
 module github.com/KarelKubat/gogit
 
-go 1.20
+go 1.25.0
 
-require github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db
+	golang.org/x/mod v0.37.0
+	golang.org/x/tools v0.47.0
+)
+
+require golang.org/x/sync v0.21.0 // indirect
@@ -0,0 +1,44 @@
+// Package gofmt checks and fixes the formatting of Go sources in-process, like `gofmt -l -d -w`.
+package gofmt
+
+import (
+	"go/format"
+	"os"
+
+	"github.com/KarelKubat/gogit/diff"
+)
+
+// Check returns the unified diff that formatting a Go source would apply, or nil when it's
+// formatted. A source that doesn't parse is an error.
+func Check(fname string) ([]string, error) {
+	src, formatted, err := formatFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	return diff.Unified(fname+".orig", fname, string(src), string(formatted)), nil
+}
+
+// Write formats a Go source in place, when it isn't formatted yet.
+func Write(fname string) error {
+	src, formatted, err := formatFile(fname)
+	if err != nil || string(src) == string(formatted) {
+		return err
+	}
+	st, err := os.Stat(fname)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fname, formatted, st.Mode().Perm())
+}
+
+func formatFile(fname string) (src, formatted []byte, err error) {
+	src, err = os.ReadFile(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+	formatted, err = format.Source(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	return src, formatted, nil
+}
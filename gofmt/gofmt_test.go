@@ -0,0 +1,63 @@
+package gofmt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	unformatted = "package x\n\nfunc f() int {\n\treturn  1\n}\n"
+	formatted   = "package x\n\nfunc f() int {\n\treturn 1\n}\n"
+)
+
+func write(t *testing.T, content string) string {
+	t.Helper()
+	fname := filepath.Join(t.TempDir(), "x.go")
+	if err := os.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fname
+}
+
+func TestCheck(t *testing.T) {
+	fname := write(t, formatted)
+	if got, err := Check(fname); err != nil || got != nil {
+		t.Errorf("Check(formatted) = %q,%v, want nil,nil", got, err)
+	}
+
+	fname = write(t, unformatted)
+	want := []string{
+		"--- " + fname + ".orig",
+		"+++ " + fname,
+		"@@ -1,5 +1,5 @@",
+		" package x",
+		" ",
+		" func f() int {",
+		"-\treturn  1",
+		"+\treturn 1",
+		" }",
+	}
+	if got, err := Check(fname); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Check(unformatted) = %q,%v, want %q,nil", got, err, want)
+	}
+
+	if _, err := Check(write(t, "package x\nfunc {")); err == nil {
+		t.Errorf("Check(unparsable) = _,nil, want error")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	fname := write(t, unformatted)
+	if err := Write(fname); err != nil {
+		t.Fatalf("Write() = %v, want nil", err)
+	}
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != formatted {
+		t.Errorf("Write() wrote %q, want %q", string(b), formatted)
+	}
+}
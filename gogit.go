@@ -1,22 +1,49 @@
 package main
 
 import (
+	"bufio"
+	"crypto/sha256"
 	"errors"
+	"flag"
 	"fmt"
-	"io/fs"
+	iofs "io/fs"
+	"maps"
+	"net/url"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/KarelKubat/gogit/action"
+	"github.com/KarelKubat/gogit/affected"
+	"github.com/KarelKubat/gogit/analyzers"
+	"github.com/KarelKubat/gogit/apicompat"
+	"github.com/KarelKubat/gogit/codeblock"
+	"github.com/KarelKubat/gogit/config"
+	"github.com/KarelKubat/gogit/conventional"
+	"github.com/KarelKubat/gogit/diff"
 	"github.com/KarelKubat/gogit/errs"
+	"github.com/KarelKubat/gogit/gofmt"
+	"github.com/KarelKubat/gogit/gomod"
+	"github.com/KarelKubat/gogit/hooks"
+	"github.com/KarelKubat/gogit/markdown"
+	"github.com/KarelKubat/gogit/modpath"
+	"github.com/KarelKubat/gogit/modules"
 	"github.com/KarelKubat/gogit/out"
 	"github.com/KarelKubat/gogit/pkggodev"
+	"github.com/KarelKubat/gogit/prepush"
+	"github.com/KarelKubat/gogit/report"
 	"github.com/KarelKubat/gogit/run"
+	"github.com/KarelKubat/gogit/snippet"
 	"github.com/KarelKubat/gogit/tag"
 	"github.com/KarelKubat/gogit/tags"
 	"github.com/KarelKubat/gogit/testframe"
+	"github.com/KarelKubat/gogit/tidy"
+	"golang.org/x/mod/modfile"
 )
 
 const (
@@ -26,15 +53,48 @@ Usage:
   # check that we're in a git repository and suggest to install hooks
   gogit hooks
 
+  # install the pre-commit and pre-push hooks, existing hooks are chained
+  gogit install-hooks
+
+  # remove the hooks that gogit installed, chained hooks are restored
+  gogit uninstall-hooks
+
   # pre-commit checks
-  gogit pre-commit  # or: gogit stdfiles && gogit gotests && gogit govets && gogit mdtoc
+  gogit pre-commit  # or: gogit stdfiles && gogit gomod && gogit gomodtidy && gogit gofmt && gogit gotests && gogit govets && gogit mdembed && gogit mdcompile && gogit mdtoc && gogit mdlinks
 
   # pre-push checks, runs the above pre-commit checks first
-  gogit pre-push    # or: gogit allcommitted && gogit haveremote && gogit gittag
+  gogit pre-push    # or: gogit allcommitted && gogit haveremote && gogit replaces && gogit gittag && gogit modpath
+  # as a hook, git passes the remote and its URL, and the pushed refs on stdin
+  gogit pre-push $REMOTE $URL < $REFS
+
+  # create the next annotated tag after asking for confirmation, the default is patch
+  gogit bump [major|minor|patch|rc]  # e.g. v1.2.3 becomes v2.0.0, v1.3.0, v1.2.4 or v1.2.4-rc.1
 
   # create a test frame for .go sources
   gogit make-test-frame a.go sub/b.go  # creates a_test.go and sub/b_test.go
 
+  # format .go sources in place, like gofmt -w
+  gogit format a.go sub/b.go
+
+  # refresh the table of contents between <!-- toc --> and <!-- /toc -->
+  gogit toc README.md docs/x.md
+
+  # refresh code blocks below <!-- embed: FILE SYMBOL --> from the Go sources
+  gogit embed README.md docs/x.md
+
+Flags:
+  --fix           execute safe suggestions (e.g. gogit format, go mod tidy) and re-run
+                  the failing check; unsafe ones (pushing, deleting) are only shown
+  --format=json   instead of colorized text, output one JSON document listing
+                  the checks, their status, messages and suggestions
+  --format=sarif  output the findings as SARIF 2.1.0, e.g. for GitHub code scanning;
+                  both formats only apply to checks, not to e.g. install-hooks or bump
+  --staged        check what's staged: unstaged changes are stashed meanwhile, and tests
+                  and analyzers only run on the packages that staged changes affect
+
+The checks that pre-commit and pre-push run can be enabled or disabled in
+.gogit.toml at the top level of the repository, see README.md.
+
 `
 
 	// `git status` output when nothing needs adding or committing
@@ -48,69 +108,394 @@ Usage:
 
 	// Seen when the local branch is ahead
 	localIsAheadStr = "Your branch is ahead"
-
-	// Tags in README.md to refresh the ToC
-	tocStart = "<!-- toc -->"
-	tocEnd   = "<!-- /toc -->"
-
-	// Default readme to be manipulated.
-	readme = "README.md"
 )
 
 var (
-	// Local/remote git tags, cached after first lookup
-	tagLocal, tagRemote *tag.Tag
+	// Local/remote git tags per module directory, cached after first lookup
+	tagsLocal  = map[string]*tag.Tag{}
+	tagsRemote = map[string]*tags.Tags{}
 
-	// Supported remote repositories
-	supportedRemoteRepos = []string{"github.com", "gitlab.com"}
+	// Nested modules without local tags, which are reported by the first check that meets them
+	untaggedWarned = map[string]bool{}
 
-	// Main package, cached after first lookup
-	mainPackageName string
+	// Repository configuration, loaded from .gogit.toml after going to the git top level
+	cfg = config.New()
+
+	// Go modules of the repository, cached after first lookup
+	repoMods []modules.Module
 
 	// Is the local repo ahead of remote, cached after first lookup
 	localAheadCached bool
 	localAheadStatus bool
+
+	// Outcome of the checks, for the summary or the --format=json document
+	rep = report.New("")
+
+	// What's being pushed when invoked as pre-push hook, nil otherwise
+	push *prepush.Push
+
+	// Staged-only mode: tests and analyzers run on the packages that staged changes affect, and
+	// unstaged changes are stashed meanwhile
+	staged  bool
+	stashed bool
+
+	// Package patterns per module directory in staged mode, cached after first lookup
+	stagedPkgs = map[string][]string{}
+
+	// Files with unstaged changes and their content hashes, before the checks ran
+	unstagedBefore map[string]string
+
+	// Flags
+	fixFlag    = flag.Bool("fix", false, "execute safe suggestions and re-run the failing check")
+	formatFlag = flag.String("format", "text", "output format: text, json or sarif")
+	stagedFlag = flag.Bool("staged", false, "check staged changes only, see staged-only in README.md")
 )
 
 func main() {
+	args := parseArgs(os.Args[1:])
+	switch *formatFlag {
+	case "text":
+	case "json", "sarif":
+		out.Record()
+	default:
+		usage()
+	}
+	// Only checks are reported as a document, other commands just act.
+	if _, ok := phases[firstArg(args)]; !ok && *formatFlag != "text" {
+		fmt.Fprintf(os.Stderr, "--format=%v only applies to checks, not to %q\n", *formatFlag, firstArg(args))
+		usage()
+	}
+
 	// `gogit make-test-frame $GO_SRC` is a special case.
-	if len(os.Args) >= 2 && os.Args[1] == "make-test-frame" {
-		if len(os.Args) == 2 {
+	if len(args) >= 1 && args[0] == "make-test-frame" {
+		if len(args) == 1 {
 			usage()
 		}
-		for _, s := range os.Args[2:] {
+		for _, s := range args[1:] {
 			check(testframe.Make(s))
 		}
 		os.Exit(0)
 	}
 
-	// All other invocations have just one argument: the action to perform.
-	if len(os.Args) != 2 {
-		usage()
+	// `gogit format $GO_SRC` is a special case too.
+	if len(args) >= 1 && args[0] == "format" {
+		if len(args) == 1 {
+			usage()
+		}
+		for _, s := range args[1:] {
+			check(gofmt.Write(s))
+		}
+		os.Exit(0)
+	}
+
+	// `gogit toc $MD_FILE` refreshes tables of contents, with the levels of the configuration.
+	if len(args) >= 1 && args[0] == "toc" {
+		if len(args) == 1 {
+			usage()
+		}
+		var files []string
+		for _, f := range args[1:] {
+			abs, err := filepath.Abs(f)
+			check(err)
+			files = append(files, abs)
+		}
+		check(gotoGitTop())
+		check(loadConfig())
+		for _, f := range files {
+			check(refreshTOC(f))
+		}
+		os.Exit(0)
+	}
+
+	// `gogit embed $MD_FILE` refreshes code blocks that embed Go declarations.
+	if len(args) >= 1 && args[0] == "embed" {
+		if len(args) == 1 {
+			usage()
+		}
+		var files []string
+		for _, f := range args[1:] {
+			abs, err := filepath.Abs(f)
+			check(err)
+			files = append(files, abs)
+		}
+		check(gotoGitTop())
+		for _, f := range files {
+			check(refreshEmbeds(f))
+		}
+		os.Exit(0)
+	}
+
+	// `gogit bump [major|minor|patch|rc]` creates a tag rather than check things.
+	if len(args) >= 1 && args[0] == "bump" {
+		part := "patch"
+		switch len(args) {
+		case 1:
+		case 2:
+			part = args[1]
+		default:
+			usage()
+		}
+		cwd, err := os.Getwd()
+		check(err)
+		check(gotoGitTop())
+		check(bump(part, cwd))
+		os.Exit(0)
 	}
 
-	checks := map[string][]func() error{
-		"hooks": {gotoGitTop, hooksInstalled},
+	// As a hook, pre-push gets the remote and its URL as arguments, and the pushed refs on stdin.
+	if len(args) == 3 && args[0] == "pre-push" {
+		p, err := prepush.Parse(args[1:], os.Stdin)
+		check(err)
+		if p.OnlyDeletes() {
+			out.Msg("only deleting refs on %v, nothing to check", p.Remote)
+			os.Exit(0)
+		}
+		push = p
+		args = args[:1]
+	}
 
-		"pre-commit": {gotoGitTop, hooksInstalled, stdFiles, goTests, goVets, mdUntab, mdToc},
-		"stdfiles":   {gotoGitTop, hooksInstalled, stdFiles},
-		"gotests":    {gotoGitTop, hooksInstalled, goTests},
-		"govets":     {gotoGitTop, hooksInstalled, goVets},
-		"mdtoc":      {mdToc},
+	// All other invocations have just one argument: the action to perform.
+	if len(args) != 1 {
+		usage()
+	}
 
-		"pre-push":     {gotoGitTop, hooksInstalled, allCommitted, haveRemote, stdFiles, goTests, goVets, mdUntab, mdToc, gitTag, pkgGoDev},
-		"allcommitted": {gotoGitTop, hooksInstalled, allCommitted},
-		"haveremote":   {gotoGitTop, hooksInstalled, haveRemote},
-		"gittag":       {gotoGitTop, hooksInstalled, gitTag},
+	// `gogit install-hooks` and `gogit uninstall-hooks` manage hooks rather than check things.
+	if args[0] == "install-hooks" || args[0] == "uninstall-hooks" {
+		check(gotoGitTop())
+		check(manageHooks(args[0] == "install-hooks"))
+		os.Exit(0)
 	}
-	funcs, ok := checks[os.Args[1]]
+
+	phaseChecks, ok := phases[args[0]]
 	if !ok {
 		usage()
 	}
-	for _, f := range funcs {
-		check(f())
+	rep.Action = args[0]
+	check(gotoGitTop())
+	check(loadConfig())
+	names := cfg.For(args[0], phaseChecks)
+	if slices.Contains(names, "restage") {
+		before, err := unstagedFiles()
+		check(err)
+		unstagedBefore = before
+	}
+	staged = *stagedFlag || (cfg.StagedOnly && args[0] == "pre-commit")
+	if staged {
+		check(stashUnstaged())
+	}
+	out.Recorded() // messages of the setup don't belong to any check
+	for i, name := range names {
+		if fs := runCheck(name); fs.Failed() {
+			restoreUnstaged()
+			finish(names[i+1:])
+			os.Exit(1)
+		}
+	}
+	restoreUnstaged()
+	finish(nil)
+}
+
+// finish outputs the summary and suggestions, or the JSON or SARIF document.
+func finish(skipped []string) {
+	for _, name := range skipped {
+		rep.Skip(name)
+	}
+	if *formatFlag == "text" {
+		summary()
+		action.Output()
+		return
+	}
+	if err := writeReport(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+		os.Exit(1)
 	}
-	action.Output()
+}
+
+func writeReport() error {
+	if *formatFlag == "sarif" {
+		return rep.WriteSARIF(os.Stdout)
+	}
+	return rep.WriteJSON(os.Stdout)
+}
+
+// firstArg returns the first positional argument, the command, or "" when there is none.
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// parseArgs parses flags that may appear anywhere on the commandline, and returns the
+// positional arguments.
+func parseArgs(args []string) []string {
+	flag.CommandLine.Usage = usage
+	positional := []string{}
+	for {
+		flag.CommandLine.Parse(args)
+		args = flag.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// runCheck runs a check by name, shows its findings and adds its outcome to the report. In fix
+// mode, a failing check has its safe suggestions executed, after which it is re-run to confirm
+// the fix.
+func runCheck(name string) *errs.Findings {
+	start := time.Now()
+	mark := action.Mark()
+	fs := runOnce(name)
+	show(fs)
+	if fs.Failed() && *fixFlag {
+		fs = fix(name, fs, mark)
+	}
+	rep.Add(fs, time.Since(start), out.Recorded(), action.Since(mark))
+	return fs
+}
+
+// fix executes the safe suggestions that a failing check made since the mark, and re-runs it.
+func fix(name string, fs *errs.Findings, mark int) *errs.Findings {
+	var fixes []string
+	for _, s := range action.Since(mark) {
+		if s.Safe {
+			fixes = append(fixes, s.Cmd)
+		}
+	}
+	if len(fixes) == 0 {
+		return fs
+	}
+	for _, fix := range fixes {
+		if _, err := run.Shell("fixing: "+fix, fix); err != nil {
+			fs = errs.New(name)
+			fs.Error(fmt.Sprintf("fix %q failed: %v", fix, err))
+			show(fs)
+			return fs
+		}
+	}
+	action.Rewind(mark)
+	forgetCaches()
+	out.Title("re-running check " + name + " after fixing")
+	fs = runOnce(name)
+	show(fs)
+	return fs
+}
+
+// runOnce runs a check, an error that it returns is added as a finding.
+func runOnce(name string) *errs.Findings {
+	fs := errs.New(name)
+	if err := checks[name](fs); err != nil {
+		fs.Error(err.Error())
+	}
+	return fs
+}
+
+// show outputs the findings of a check. In JSON or SARIF format they are only part of the report.
+func show(fs *errs.Findings) {
+	if *formatFlag != "text" {
+		return
+	}
+	for _, f := range fs.List {
+		lines := append([]string{f.String()}, f.Suggestions...)
+		switch f.Severity {
+		case errs.Error:
+			out.Error(lines...)
+		case errs.Warn:
+			out.Warn(lines...)
+		default:
+			out.Msg("%v", strings.Join(lines, "\n"))
+		}
+	}
+}
+
+// summary outputs the outcome of each check, including the ones that were skipped.
+func summary() {
+	if len(rep.Checks) < 2 {
+		return
+	}
+	out.Title("summary:")
+	for _, c := range rep.Checks {
+		line := fmt.Sprintf("  %-14v %v", c.Name, c.Summary)
+		switch c.Status {
+		case report.StatusError:
+			out.Error(line)
+		case report.StatusWarning, report.StatusSkipped:
+			out.Warn(line)
+		default:
+			out.Msg("%v", line)
+		}
+	}
+}
+
+// forgetCaches drops all cached lookups, so that a re-run check sees the effects of fixes.
+func forgetCaches() {
+	run.Forget()
+	tagsLocal = map[string]*tag.Tag{}
+	tagsRemote = map[string]*tags.Tags{}
+	untaggedWarned = map[string]bool{}
+	repoMods = nil
+	stagedPkgs = map[string][]string{}
+	localAheadCached = false
+}
+
+// Checks that can be enabled or disabled in the configuration, by name. A check reports
+// findings; a returned error is added as a finding with severity errs.Error.
+var checks = map[string]func(fs *errs.Findings) error{
+	"hooks":        hooksInstalled,
+	"stdfiles":     stdFiles,
+	"gotests":      goTests,
+	"govets":       goVets,
+	"gomod":        goMod,
+	"replaces":     replaces,
+	"gomodtidy":    goModTidy,
+	"gofmt":        goFmt,
+	"mduntab":      mdUntab,
+	"mdembed":      mdEmbed,
+	"mdcompile":    mdCompile,
+	"mdtoc":        mdToc,
+	"mdlinks":      mdLinks,
+	"restage":      restage,
+	"allcommitted": allCommitted,
+	"haveremote":   haveRemote,
+	"gittag":       gitTag,
+	"modpath":      modPath,
+	"pkggodev":     pkgGoDev,
+}
+
+// Actions on the commandline and the checks that they run by default.
+var phases = map[string][]string{
+	"hooks": {"hooks"},
+
+	"pre-commit": {"hooks", "stdfiles", "gomod", "gomodtidy", "gofmt", "gotests", "govets", "mduntab", "mdembed", "mdcompile", "mdtoc", "mdlinks", "restage"},
+	"stdfiles":   {"hooks", "stdfiles"},
+	"gomod":      {"hooks", "gomod"},
+	"gomodtidy":  {"hooks", "gomodtidy"},
+	"gofmt":      {"hooks", "gofmt"},
+	"gotests":    {"hooks", "gotests"},
+	"govets":     {"hooks", "govets"},
+	"mduntab":    {"mduntab"},
+	"mdembed":    {"mdembed"},
+	"mdcompile":  {"mdcompile"},
+	"mdtoc":      {"mdtoc"},
+	"mdlinks":    {"mdlinks"},
+
+	"pre-push":     {"hooks", "allcommitted", "haveremote", "stdfiles", "gomod", "replaces", "gomodtidy", "gofmt", "gotests", "govets", "mduntab", "mdembed", "mdcompile", "mdtoc", "mdlinks", "gittag", "modpath", "pkggodev"},
+	"allcommitted": {"hooks", "allcommitted"},
+	"haveremote":   {"hooks", "haveremote"},
+	"gittag":       {"hooks", "gittag"},
+	"modpath":      {"hooks", "modpath"},
+	"replaces":     {"hooks", "replaces"},
+}
+
+// Parts of a version that `gogit bump` can increase, and how the next tag is computed.
+var bumps = map[string]func(*tag.Tag) *tag.Tag{
+	"major": (*tag.Tag).NextMajor,
+	"minor": (*tag.Tag).NextMinor,
+	"patch": (*tag.Tag).NextPatch,
+	"rc":    (*tag.Tag).NextPrerelease,
 }
 
 func usage() {
@@ -119,26 +504,148 @@ func usage() {
 }
 
 func check(err error) {
-	if err != nil {
-		out.Error(err.Error())
-		action.Output()
+	if err == nil {
+		return
+	}
+	if *formatFlag != "text" {
+		rep.Fail(err)
+		writeReport()
 		os.Exit(1)
 	}
+	out.Error(err.Error())
+	action.Output()
+	os.Exit(1)
+}
+
+// hooksDir returns the directory where git looks for hooks. This honors core.hooksPath, and in a
+// linked worktree (where .git is a file) it's the hooks directory of the main repository.
+func hooksDir() (string, error) {
+	lines, err := run.Exec("finding git hooks directory",
+		[]string{"git", "rev-parse", "--git-path", "hooks"})
+	if err != nil {
+		return "", err
+	}
+	if len(lines) != 1 {
+		lines = append(lines, "need exactly 1 output to find the git hooks directory")
+		return "", errors.New(strings.Join(lines, "\n"))
+	}
+	return lines[0], nil
+}
+
+func hooksInstalled(fs *errs.Findings) error {
+	dir, err := hooksDir()
+	if err != nil {
+		return err
+	}
+	out.Title(fmt.Sprintf("checking that hooks in %v are installed", dir))
+	missing := false
+	for _, hook := range hooks.Names {
+		path := filepath.Join(dir, hook)
+		gogit, err := hooks.IsGogit(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			fs.Error(fmt.Sprintf("hook %q doesn't exist", path))
+			missing = true
+		case err != nil:
+			fs.Error(fmt.Sprintf("hook %q can't be read: %v", path, err))
+		case !gogit:
+			fs.Error(fmt.Sprintf("hook %q exists but doesn't run gogit, it will be chained", path))
+			missing = true
+		}
+	}
+	if missing {
+		fs.Info(
+			"to install the hooks, run:",
+			action.Fix("%v install-hooks", self()))
+	}
+	return nil
+}
+
+// bump computes the next tag from the local one of the module in dir, and creates it when the
+// user confirms.
+func bump(part, dir string) error {
+	next, ok := bumps[part]
+	if !ok {
+		return fmt.Errorf("can't bump %q, use one of major, minor, patch or rc", part)
+	}
+	m, err := moduleAt(dir)
+	if err != nil {
+		return err
+	}
+	localTag, err := localGitTag(m)
+	if err != nil {
+		return err
+	}
+	nextTag := tagName(m, next(localTag))
+	out.Msg("local tag: %v, next %v tag: %v", tagName(m, localTag), part, nextTag)
+	if !confirm(fmt.Sprintf("create annotated tag %v?", nextTag)) {
+		out.Msg("not tagging")
+		return nil
+	}
+	if _, err := run.Shell("creating tag "+nextTag,
+		fmt.Sprintf("git tag -a %v -m %v", nextTag, nextTag)); err != nil {
+		return err
+	}
+	out.Msg("tag %v created, to publish it, run:", nextTag)
+	action.Suggest("git push %v %v", pushRemote(), nextTag)
+	action.Output()
+	return nil
+}
+
+// moduleAt returns the module that contains a directory: the most deeply nested one, or the top
+// level.
+func moduleAt(dir string) (modules.Module, error) {
+	mods, err := tagSeries()
+	if err != nil {
+		return modules.Module{}, err
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	rel, err := filepath.Rel(gitTop, dir)
+	if err != nil {
+		return modules.Module{}, err
+	}
+	if m, ok := modules.Of(mods, filepath.ToSlash(rel)); ok {
+		return m, nil
+	}
+	return modules.Module{Dir: "."}, nil
+}
+
+// confirm asks a yes/no question on stdin, the default is no.
+func confirm(question string) bool {
+	fmt.Printf("%v [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
 }
 
-func hooksInstalled() error {
-	out.Title("checking that .git/hooks are installed")
-	for _, hook := range []string{"pre-commit", "pre-push"} {
-		path := fmt.Sprintf(".git/hooks/%v", hook)
-		_, err := os.Stat(path)
+func manageHooks(install bool) error {
+	dir, err := hooksDir()
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks.Names {
+		var what string
+		if install {
+			what, err = hooks.Install(dir, hook)
+		} else {
+			what, err = hooks.Uninstall(dir, hook)
+		}
 		if err != nil {
-			errs.Add(
-				fmt.Sprintf("hook %q doesn't exist, run:", path),
-				action.Suggest("echo exec gogit %v > %v", hook, path),
-				action.Suggest("chmod +x %v", path))
+			return err
 		}
+		out.Msg("%v", what)
 	}
-	return errs.Err()
+	return nil
 }
 
 var gitTop string
@@ -147,277 +654,1101 @@ func gotoGitTop() error {
 	lines, err := run.Exec("finding top level git folder",
 		[]string{"git", "rev-parse", "--show-toplevel"})
 	if err != nil {
-		return errs.Add(
-			err.Error()+", try:",
-			action.Suggest("git init"))
+		return errors.New(strings.Join([]string{
+			err.Error() + ", try:",
+			action.Suggest("git init"),
+		}, "\n"))
 	}
 	if len(lines) != 1 {
 		lines = append(lines, "need exactly 1 output to find the top level git folder")
-		return errs.Add(lines...)
+		return errors.New(strings.Join(lines, "\n"))
 	}
 	gitTop = lines[0]
 	out.Msg("top level git folder: %q\n", gitTop)
 	if err := os.Chdir(gitTop); err != nil {
-		return errs.Add(fmt.Sprintf("cannot chdir to top level git folder: %v", err))
+		return fmt.Errorf("cannot chdir to top level git folder: %v", err)
+	}
+	return nil
+}
+
+func loadConfig() error {
+	c, err := config.Load(config.FileName)
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	for name := range checks {
+		names = append(names, name)
 	}
+	if err := c.Validate(phases, names, analyzers.Names()); err != nil {
+		return err
+	}
+	cfg = c
 	return nil
 }
 
-func stdFiles() error {
+// stdFiles checks the required files: go.mod and go.sum in each module, the others at the top
+// level.
+func stdFiles(fs *errs.Findings) error {
 	out.Title("checking that standard files are present")
-	for _, md := range []string{readme, "LICENSE.md"} {
-		if _, err := os.Stat(md); err != nil {
-			errs.Add(fmt.Sprintf("file %v not found, create one and retry", md))
+	for _, f := range cfg.RequiredFiles {
+		if f == modules.ModFile || f == modules.SumFile {
+			continue
+		}
+		if _, err := os.Stat(f); err == nil {
+			continue
+		}
+		switch f {
+		case ".gitignore":
+			fs.Error(
+				"`.gitignore` not found, create one and retry, at a minimum run:",
+				action.Fix("echo .git > .gitignore"))
+		default:
+			fs.Error(fmt.Sprintf("file %v not found, create one and retry", f))
 		}
 	}
-	if _, err := os.Stat(".gitignore"); err != nil {
-		errs.Add(
-			"`.gitignore` not found, create one and retry, at a minimum run:",
-			action.Suggest("echo .git > .gitignore"))
+	mods, err := repoModules()
+	if err != nil {
+		return err
+	}
+	if len(mods) == 0 {
+		if slices.Contains(cfg.RequiredFiles, modules.ModFile) {
+			fs.Error(
+				"`go.mod` not found, at a minimum run:",
+				action.Suggest("go mod init <module path>"),
+				action.Suggest("go mod tidy"))
+		}
+		return nil
 	}
-	if _, err := os.Stat("go.mod"); err != nil {
-		errs.Add(
-			"`go.mod` not found, at a minimum run:",
-			action.Suggest("go mod init"),
-			action.Suggest("go mod tidy"))
+	for _, m := range mods {
+		if err := stdFilesModule(fs, m); err != nil {
+			return err
+		}
 	}
-	return errs.Err()
+	return nil
 }
 
-func goTests() error {
-	out.Title("checking for go tests")
-	srcs := map[string]struct{}{}
-	tests := map[string]struct{}{}
-	filepath.WalkDir(".", func(p string, d fs.DirEntry, err error) error {
+// stdFilesModule checks that a module has a go.sum when it requires other modules, or when go.sum
+// is a required file.
+func stdFilesModule(fs *errs.Findings, m modules.Module) error {
+	if _, err := os.Stat(m.File(modules.SumFile)); err == nil {
+		return nil
+	}
+	f, err := gomod.Parse(m.File(modules.ModFile))
+	if err != nil {
+		return err
+	}
+	if len(f.Require) == 0 && !slices.Contains(cfg.RequiredFiles, modules.SumFile) {
+		return nil
+	}
+	fs.Error(
+		fmt.Sprintf("`go.sum`%v not found, run:", forModule(m)),
+		action.Fix("go -C %v mod tidy", m.Dir)).At(m.File(modules.ModFile), 0)
+	return nil
+}
+
+// goFiles returns the .go files in the repository, tests included, skipping the directories that
+// the go tool ignores, e.g. testdata with deliberately broken fixtures.
+func goFiles() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(".", func(p string, d iofs.DirEntry, err error) error {
 		if err != nil {
-			errs.Add(err.Error())
 			return err
 		}
-		switch {
-		case strings.HasSuffix(p, "_test.go"):
-			tests[p] = struct{}{}
-		case strings.HasSuffix(p, ".go"):
-			srcs[p] = struct{}{}
+		if d.IsDir() && p != "." && modules.Ignored(d.Name()) {
+			return filepath.SkipDir
+		}
+		if strings.HasSuffix(p, ".go") {
+			files = append(files, p)
 		}
 		return nil
 	})
-	if errs.Err() != nil {
-		return errs.Err()
+	return files, err
+}
+
+func goTests(fs *errs.Findings) error {
+	out.Title("checking for go tests")
+	files, err := goFiles()
+	if err != nil {
+		return err
+	}
+	srcs := map[string]struct{}{}
+	tests := map[string]struct{}{}
+	for _, p := range files {
+		if strings.HasSuffix(p, "_test.go") {
+			tests[p] = struct{}{}
+		} else {
+			srcs[p] = struct{}{}
+		}
 	}
 	testsFound := false
-	suggestFrames := []string{}
 	for s := range srcs {
 		wantTest := strings.Replace(s, ".go", "_test.go", 1)
 		if _, ok := tests[wantTest]; !ok {
-			errs.Add(fmt.Sprintf("go source %q lacks a test %q", s, wantTest))
-			suggestFrames = append(suggestFrames, s)
+			fs.Error(
+				fmt.Sprintf("go source lacks a test %q, at a minimum run:", wantTest),
+				action.Fix("%v make-test-frame %v", self(), shellQuote(s))).At(s, 0)
 		} else {
 			testsFound = true
 		}
 	}
-	if testsFound {
-		_, err := run.Exec("running go tests",
-			[]string{"go", "test", "-race", "-cover", "./..."})
+	if !testsFound {
+		return nil
+	}
+	mods, err := repoModules()
+	if err != nil {
+		return err
+	}
+	for _, m := range mods {
+		pkgs, err := packagesOf(m)
 		if err != nil {
-			errs.Add(err.Error())
+			return err
 		}
-	}
-	if len(suggestFrames) > 0 {
-		errs.Add("at a minimum run:")
-		for _, frame := range suggestFrames {
-			action.Suggest("gogit make-test-frame %v", frame)
+		if len(pkgs) == 0 {
+			continue
+		}
+		_, err = run.Exec("running go tests"+forModule(m),
+			append([]string{"go", "-C", m.Dir, "test", "-race", "-cover"}, pkgs...))
+		if err != nil {
+			fs.Error(fmt.Sprintf("go test%v: %v", forModule(m), err)).At(m.File(modules.ModFile), 0)
 		}
 	}
-	return errs.Err()
+	return nil
 }
 
-func allCommitted() error {
+func allCommitted(fs *errs.Findings) error {
 	lines, err := run.Exec("checking that everything is locally committed",
 		[]string{"git", "status"})
 	if err != nil {
-		errs.Add(err.Error())
-		return errs.Err()
+		return err
+	}
+	for _, l := range lines {
+		if strings.Contains(l, statusOK) {
+			return nil
+		}
+	}
+	fs.Info(strings.Join(lines, "\n"))
+	fs.Error(
+		"not everything is commited, run:",
+		action.Suggest("git status              # to check what's needed"),
+		action.Suggest("git add $FILE(s)        # to add new files if needed"),
+		action.Suggest("git commit -m $MESSAGE  # to locally commit"))
+	return nil
+}
+
+// goVets runs the configured analyzers in-process on the packages of each module: the go vet
+// suite and extras such as nilness.
+func goVets(fs *errs.Findings) error {
+	as, err := analyzers.Select(cfg.AnalyzersFor(analyzers.Defaults()))
+	if err != nil {
+		return err
+	}
+	mods, err := repoModules()
+	if err != nil {
+		return err
+	}
+	for _, m := range mods {
+		pkgs, err := packagesOf(m)
+		if err != nil {
+			return err
+		}
+		if len(pkgs) == 0 {
+			continue
+		}
+		out.Title("running analyzers on local packages" + forModule(m))
+		diags, err := analyzers.Run(m.Dir, pkgs, as)
+		if err != nil {
+			return err
+		}
+		for _, d := range diags {
+			f := fs.Error(fmt.Sprintf("%v (%v)", d.Msg, d.Analyzer))
+			if d.File != "" {
+				f.At(m.File(d.File), d.Line).WithColumn(d.Column)
+			}
+		}
+	}
+	return nil
+}
+
+// goMod parses the go.mod files, and verifies that their go directive isn't newer than the
+// installed Go and that their toolchain line is consistent.
+func goMod(fs *errs.Findings) error {
+	// GOTOOLCHAIN=local, or the go command would switch to the toolchain that go.mod asks for.
+	lines, err := run.Exec("checking the installed go version",
+		[]string{"env", "GOTOOLCHAIN=local", "go", "env", "GOVERSION"})
+	if err != nil {
+		return err
+	}
+	installed := strings.TrimSpace(strings.Join(lines, ""))
+	return goModIssues(fs, "checking go.mod go and toolchain directives", func(f *modfile.File) []gomod.Issue {
+		return gomod.Versions(f, installed)
+	})
+}
+
+// replaces verifies that no go.mod replaces a module by a local directory, which only exists on
+// this machine and breaks the module for others.
+func replaces(fs *errs.Findings) error {
+	return goModIssues(fs, "checking go.mod files for local replace directives", gomod.LocalReplaces)
+}
+
+// goModIssues reports the issues that inspect finds in the go.mod of each module.
+func goModIssues(fs *errs.Findings, title string, inspect func(*modfile.File) []gomod.Issue) error {
+	out.Title(title)
+	mods, err := repoModules()
+	if err != nil {
+		return err
+	}
+	for _, m := range mods {
+		fname := m.File(modules.ModFile)
+		f, err := gomod.Parse(fname)
+		if err != nil {
+			fs.Error(err.Error()).At(fname, 0)
+			continue
+		}
+		for _, is := range inspect(f) {
+			var suggestions []string
+			if is.Edit != "" {
+				suggestions = append(suggestions, action.Suggest("go -C %v mod edit %v", m.Dir, is.Edit))
+			}
+			fs.Add(is.Severity, is.Msg, suggestions...).At(fname, is.Line)
+		}
+	}
+	return nil
+}
+
+// goFmt verifies that the .go files are formatted, and shows the diff that formatting would apply
+// when they aren't.
+func goFmt(fs *errs.Findings) error {
+	out.Title("checking that go sources are formatted")
+	files, err := goFiles()
+	if err != nil {
+		return err
+	}
+	var unformatted []string
+	for _, f := range files {
+		d, err := gofmt.Check(f)
+		if err != nil {
+			fs.Error(err.Error()).At(f, 0)
+			continue
+		}
+		if d == nil {
+			continue
+		}
+		unformatted = append(unformatted, f)
+		fs.Error(fmt.Sprintf("not formatted, gofmt would change:\n%v", strings.Join(d, "\n"))).At(f, 0)
+	}
+	if len(unformatted) > 0 {
+		// No git add: in pre-commit, the restage check decides what can be re-added.
+		var quoted []string
+		for _, f := range unformatted {
+			quoted = append(quoted, shellQuote(f))
+		}
+		fs.Info("to format, run:", action.Fix("%v format %v", self(), strings.Join(quoted, " ")))
+	}
+	return nil
+}
+
+// goModTidy verifies that `go mod tidy` wouldn't change go.mod or go.sum, and shows the diff when
+// it would. It runs offline against the local module cache, modules that aren't there are reported.
+func goModTidy(fs *errs.Findings) error {
+	mods, err := repoModules()
+	if err != nil {
+		return err
+	}
+	for _, m := range mods {
+		// Captured, as the diff and missing modules are reported as findings.
+		lines, err := run.Capture("checking that go.mod and go.sum are tidy"+forModule(m),
+			offlineGo(m.Dir, "mod", "tidy", "-diff"))
+		if err != nil && tidy.Unsupported(lines) {
+			lines, err = tidyScratch(m)
+		}
+		if err == nil {
+			out.Msg("go.mod and go.sum are tidy")
+			continue
+		}
+		r := tidy.Parse(lines)
+		if len(r.Missing) > 0 {
+			fs.Error(
+				fmt.Sprintf("not in the local module cache: %v, to fetch, run:", strings.Join(r.Missing, ", ")),
+				action.Suggest("go -C %v mod tidy", m.Dir)).At(m.File(modules.ModFile), 0)
+		}
+		if len(r.Diff) > 0 {
+			fs.Error(
+				fmt.Sprintf("go.mod and go.sum aren't tidy, `go mod tidy` would change:\n%v\nto tidy, run:",
+					strings.Join(r.Diff, "\n")),
+				action.Fix("go -C %v mod tidy", m.Dir)).At(m.File(modules.ModFile), 0)
+		}
+		if len(r.Missing) == 0 && len(r.Diff) == 0 {
+			r.Rest = append(r.Rest, err.Error())
+		}
+		if len(r.Rest) > 0 {
+			fs.Error(strings.Join(r.Rest, "\n"))
+		}
+	}
+	return nil
+}
+
+// tidyScratch tidies a scratch copy of go.mod and go.sum, for go commands that lack
+// `go mod tidy -diff`, and returns the output as `-diff` would.
+func tidyScratch(m modules.Module) ([]string, error) {
+	dir, err := os.MkdirTemp("", "gogit-tidy-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	files := []string{modules.ModFile, modules.SumFile}
+	for _, f := range files {
+		b, err := os.ReadFile(m.File(f))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, f), b, 0644); err != nil {
+			return nil, err
+		}
+	}
+	if lines, err := run.Capture("tidying a scratch copy of go.mod"+forModule(m),
+		offlineGo(m.Dir, "mod", "tidy", "-modfile="+filepath.Join(dir, modules.ModFile))); err != nil {
+		return lines, err
+	}
+	var diff []string
+	for _, f := range files {
+		if !exists(m.File(f)) && !exists(filepath.Join(dir, f)) {
+			continue
+		}
+		lines, err := run.Capture("comparing "+m.File(f), []string{"diff", "-u", "-N", m.File(f), filepath.Join(dir, f)})
+		if err != nil && len(lines) == 0 {
+			return nil, err
+		}
+		if len(lines) > 0 {
+			diff = append(diff, fmt.Sprintf("diff current/%v tidy/%v", f, f))
+			diff = append(diff, lines...)
+		}
+	}
+	if len(diff) > 0 {
+		return diff, errors.New("go.mod and go.sum aren't tidy")
+	}
+	return nil, nil
+}
+
+// exists returns true when a file exists.
+func exists(fname string) bool {
+	_, err := os.Stat(fname)
+	return err == nil
+}
+
+// offlineGo returns a go commandline for a module that doesn't access the network: modules must
+// be in the local module cache and the local toolchain is used.
+func offlineGo(dir string, args ...string) []string {
+	return append([]string{"env", "GOPROXY=off", "GOTOOLCHAIN=local", "go", "-C", dir}, args...)
+}
+
+/* Ouch.. this badly messes up READMEs. Not using.
+func mdUntab() error {
+	_, err := os.Stat("README.md")
+	if err != nil {
+		return err
+	}
+	lines, err := run.Exec("untabifying README.MD using tab2space",
+		[]string{"tab2space", "-lf", "README.md"})
+	if err != nil {
+		out.Error("(Not fatal) tab2space failed")
+		return nil
+	}
+	if err = os.WriteFile("README.md", []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return err
+	}
+	return nil
+}
+*/
+
+// mdUntab replaces the tabs in the fenced code blocks of the tracked Markdown files by spaces, as
+// GitHub renders them eight wide. Blocks in a language whose tabs are significant (e.g. make) are
+// left alone.
+func mdUntab(fs *errs.Findings) error {
+	files, err := markdownFiles()
+	if err != nil {
+		return err
+	}
+	out.Title("untabbing code blocks in markdown files")
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(string(b), "\n")
+		changed := false
+		unclosed := false
+		for _, fence := range markdown.Fences(string(b)) {
+			if fence.End == 0 {
+				fs.Error("code block opened, but not closed").At(f, fence.Start)
+				unclosed = true
+				continue
+			}
+			if slices.ContainsFunc(cfg.Untab.KeepTabs, func(lang string) bool {
+				return strings.EqualFold(lang, fence.Lang())
+			}) {
+				continue
+			}
+			// Lines are 1-based, so the opening fence's number is the index of the first code line.
+			for i := fence.Start; i < fence.End-1; i++ {
+				if u := markdown.Untab(lines[i]); u != lines[i] {
+					lines[i] = u
+					changed = true
+				}
+			}
+		}
+		if unclosed || !changed {
+			continue
+		}
+		// Ensure \n at the end.
+		if lines[len(lines)-1] != "" {
+			lines = append(lines, "")
+		}
+		if err := writeAtomic(f, []byte(strings.Join(lines, "\n"))); err != nil {
+			return err
+		}
+		out.Msg("untabbed code blocks in %v", f)
+	}
+	return nil
+}
+
+// writeAtomic replaces a file by writing a temporary file next to it and renaming that over it, so
+// that the file is never left half-written. The permissions of the file are kept.
+func writeAtomic(fname string, data []byte) error {
+	st, err := os.Stat(fname)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fname), "."+filepath.Base(fname)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly after the rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), st.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fname)
+}
+
+// mdEmbed verifies that the code blocks below embed directives in the tracked Markdown files hold
+// the Go declarations that the directives name.
+func mdEmbed(fs *errs.Findings) error {
+	files, err := markdownFiles()
+	if err != nil {
+		return err
+	}
+	out.Title("checking embedded code in markdown files")
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		content := string(b)
+		updated, problems := snippet.Refresh(f, content)
+		for _, p := range problems {
+			fs.Error(p.Msg).At(f, p.Line)
+		}
+		if updated != content {
+			fs.Error(
+				fmt.Sprintf("embedded code is stale, refreshing would change:\n%v\nto refresh, run:",
+					strings.Join(diff.Unified(f+".orig", f, content, updated), "\n")),
+				action.Fix("%v embed %v", self(), shellQuote(f))).At(f, 0)
+		}
+	}
+	return nil
+}
+
+// mdCompile type-checks the Go code blocks of the readme against the packages of its module, so
+// that examples don't rot when the API changes.
+func mdCompile(fs *errs.Findings) error {
+	b, err := os.ReadFile(cfg.Readme)
+	if err != nil {
+		return err
+	}
+	blocks := codeblock.Blocks(string(b))
+	if len(blocks) == 0 {
+		return nil
+	}
+	mods, err := repoModules()
+	if err != nil {
+		return err
+	}
+	m, ok := modules.Of(mods, cfg.Readme)
+	if !ok {
+		fs.Warn("not in a Go module, code blocks can't be type-checked").At(cfg.Readme, 0)
+		return nil
+	}
+	out.Title("type-checking go code blocks in " + cfg.Readme)
+	diags, err := codeblock.Check(m.Dir, cfg.Readme, blocks)
+	if err != nil {
+		return err
+	}
+	for _, d := range diags {
+		fs.Error(d.Msg).At(cfg.Readme, d.Line).WithColumn(d.Column)
+	}
+	if len(diags) > 0 {
+		fs.Info(fmt.Sprintf("code blocks that aren't meant to compile can be opened with ```go %v",
+			codeblock.NoCompile))
+	}
+	return nil
+}
+
+// refreshEmbeds refreshes the code blocks below embed directives in a Markdown file. Directives
+// that can't be resolved are an error, after the others are refreshed.
+func refreshEmbeds(fname string) error {
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	updated, problems := snippet.Refresh(fname, string(b))
+	if updated != string(b) {
+		if err := writeAtomic(fname, []byte(updated)); err != nil {
+			return err
+		}
+		out.Msg("refreshed embedded code in %v", fname)
+	}
+	var msgs []string
+	for _, p := range problems {
+		msgs = append(msgs, fmt.Sprintf("%v:%v: %v", fname, p.Line, p.Msg))
+	}
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "\n"))
+	}
+	return nil
+}
+
+// mdToc refreshes the table of contents of the readme, or in check-only mode, verifies that it's
+// up to date.
+func mdToc(fs *errs.Findings) error {
+	out.Title("refreshing table of contents in " + cfg.Readme)
+	b, err := os.ReadFile(cfg.Readme)
+	if err != nil {
+		return err
+	}
+	content := string(b)
+	if !strings.Contains(content, markdown.TOCStart) && !strings.Contains(content, markdown.TOCEnd) {
+		fs.Warn(
+			"no Table of Contents section, to have the TOC automatically updated:",
+			action.Suggest("add   %v    to "+cfg.Readme+" (at first column)", markdown.TOCStart),
+			action.Suggest("add   %v   to "+cfg.Readme+" (at first column)", markdown.TOCEnd)).At(cfg.Readme, 0)
+		return nil
+	}
+	if !cfg.TOC.CheckOnly {
+		return refreshTOC(cfg.Readme)
+	}
+	updated, err := markdown.UpdateTOC(content, cfg.TOC.MinLevel, cfg.TOC.MaxLevel)
+	if err != nil {
+		return fmt.Errorf("%v: %v", cfg.Readme, err)
+	}
+	if updated != content {
+		fs.Error(
+			fmt.Sprintf("table of contents is stale, refreshing would change:\n%v\nto refresh, run:",
+				strings.Join(diff.Unified(cfg.Readme+".orig", cfg.Readme, content, updated), "\n")),
+			action.Fix("%v toc %v", self(), shellQuote(cfg.Readme))).At(cfg.Readme, 0)
+	}
+	return nil
+}
+
+// refreshTOC regenerates the table of contents of a Markdown file, with the configured levels.
+func refreshTOC(fname string) error {
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	updated, err := markdown.UpdateTOC(string(b), cfg.TOC.MinLevel, cfg.TOC.MaxLevel)
+	if err != nil {
+		return fmt.Errorf("%v: %v", fname, err)
+	}
+	if updated == string(b) {
+		return nil
+	}
+	out.Msg("refreshed table of contents in %v", fname)
+	return writeAtomic(fname, []byte(updated))
+}
+
+// mdLinks verifies that the links in the tracked Markdown files resolve: relative links must point
+// at existing files, and #anchors at headings. External links are not fetched, but can be listed.
+func mdLinks(fs *errs.Findings) error {
+	files, err := markdownFiles()
+	if err != nil {
+		return err
+	}
+	out.Title("checking links in markdown files")
+	anchors := map[string]map[string]struct{}{} // per file, read when needed
+	anchorsOf := func(fname string) (map[string]struct{}, error) {
+		if a, ok := anchors[fname]; ok {
+			return a, nil
+		}
+		b, err := os.ReadFile(fname)
+		if err != nil {
+			return nil, err
+		}
+		anchors[fname] = markdown.Anchors(string(b))
+		return anchors[fname], nil
+	}
+	var external []string
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		for _, l := range markdown.Links(string(b)) {
+			if l.External() {
+				external = append(external, fmt.Sprintf("%v:%v: %v", f, l.Line, l.Target))
+				continue
+			}
+			p, anchor := l.Split()
+			target := f
+			if p != "" {
+				if unescaped, err := url.PathUnescape(p); err == nil {
+					p = unescaped
+				}
+				if strings.HasPrefix(p, "/") {
+					target = path.Clean(strings.TrimPrefix(p, "/"))
+				} else {
+					target = path.Join(path.Dir(f), p)
+				}
+				if _, err := os.Stat(target); err != nil {
+					fs.Error(fmt.Sprintf("link %q: %v doesn't exist", l.Target, target)).At(f, l.Line).WithColumn(l.Column)
+					continue
+				}
+			}
+			if anchor == "" || !strings.HasSuffix(target, ".md") {
+				continue
+			}
+			as, err := anchorsOf(target)
+			if err != nil {
+				return err
+			}
+			if unescaped, err := url.PathUnescape(anchor); err == nil {
+				anchor = unescaped
+			}
+			if _, ok := as[anchor]; !ok {
+				fs.Error(fmt.Sprintf("link %q: no heading or anchor %q in %v", l.Target, anchor, target)).
+					At(f, l.Line).WithColumn(l.Column)
+			}
+		}
+	}
+	if cfg.Links.ReportExternal && len(external) > 0 {
+		fs.Info(fmt.Sprintf("external links, not checked:\n%v", strings.Join(external, "\n")))
+	}
+	return nil
+}
+
+// markdownFiles returns the tracked Markdown files.
+func markdownFiles() ([]string, error) {
+	return run.Exec("finding markdown files",
+		[]string{"git", "-c", "core.quotePath=false", "ls-files", "--", "*.md"})
+}
+
+func gitTag(fs *errs.Findings) error {
+	out.Title("checking git tags")
+	mods, err := tagSeries()
+	if err != nil {
+		return err
+	}
+	if push != nil && len(push.Tags()) > 0 {
+		for _, r := range push.Tags() {
+			if moduleOfTag(mods, r.Tag()) == nil {
+				fs.Error(fmt.Sprintf("pushed tag %q doesn't belong to any module, expected one of the prefixes %v",
+					r.Tag(), tagPrefixes(mods)))
+			}
+		}
+	}
+	for _, m := range mods {
+		if err := gitTagModule(fs, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gitTagModule checks the tag series of one module.
+func gitTagModule(fs *errs.Findings, m modules.Module) error {
+	localTag, err := localGitTag(m)
+	if skipUntagged(fs, m, err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	remoteTag, err := remoteGitTag(m)
+	if err != nil {
+		return err
+	}
+	out.Msg("local tag%v: %q, remote tag: %q", forModule(m), localTag, remoteTag)
+	var required *tag.Tag
+	changed := true
+	if !remoteTag.IsZero() {
+		required, changed = requiredTag(fs, m, remoteTag)
+	}
+	if push != nil && len(push.Tags()) > 0 {
+		if refs := pushedRefs(m); len(refs) > 0 {
+			pushedTags(fs, m, refs, remoteTag, required, apiTag(fs, m))
+		}
+		return nil
+	}
+	ahead, err := localIsAhead()
+	if err != nil {
+		return err
+	}
+	if !remoteTag.IsZero() && !localTag.Greater(remoteTag) && ahead && changed {
+		if major := apiTag(fs, m); major != nil && major.Greater(required) {
+			required = major
+		}
+		alternatives := []string{}
+		for _, alt := range []*tag.Tag{remoteTag.NextMinor(), remoteTag.NextMajor()} {
+			if alt.Greater(required) {
+				alternatives = append(alternatives, tagName(m, alt))
+			}
+		}
+		comment := ""
+		if len(alternatives) > 0 {
+			comment = "  # or " + strings.Join(alternatives, ", ")
+		}
+		fs.Error(
+			fmt.Sprintf("the local tag%v should indicate a higher version than the remote one, increase the local tag first, run:", forModule(m)),
+			action.Suggest("# ---- either: increase the tag ID and push ----"),
+			action.Suggest("git tag -a %v -m %v%v", tagName(m, required), tagName(m, required), comment),
+			action.Suggest("git push"),
+			action.Suggest("git push %v %v", pushRemote(), tagName(m, required)))
+		fs.Info(
+			"alternatively, to stay on the same tag number, run:",
+			action.Suggest("# ---- or: stay on the same tag ID ----"),
+			action.Suggest("git push --no-verify"))
+		return nil
+	}
+	if required != nil && localTag.Greater(remoteTag) {
+		if !checkBump(fs, m, localTag, required) || !checkAPI(fs, m, localTag, apiTag(fs, m)) {
+			return nil
+		}
+	}
+	if !localTag.IsZero() && !localTag.Equal(remoteTag) {
+		fs.Info(
+			fmt.Sprintf("local tag %v will need pushing to remote, remember to run:", tagName(m, localTag)),
+			action.Suggest("git push %v %v", pushRemote(), tagName(m, localTag)))
+	} else if localTag.IsZero() {
+		fs.Info(
+			fmt.Sprintf("local tag %v is still at zero; to increase to a working set, run:", tagName(m, localTag)),
+			action.Suggest("# --- if needed, increase tag to a supported version"),
+			action.Suggest("git tag -a %v -m %v", m.TagName("v0.0.1"), m.TagName("v0.0.1")))
+	}
+	return nil
+}
+
+// tagSeries returns the modules that have their own tag series. Without go.mod, the repository is
+// tagged as a whole.
+func tagSeries() ([]modules.Module, error) {
+	mods, err := repoModules()
+	if err != nil {
+		return nil, err
+	}
+	if len(mods) == 0 {
+		return []modules.Module{{Dir: "."}}, nil
+	}
+	return mods, nil
+}
+
+// moduleOfTag returns the module that a tag belongs to, or nil.
+func moduleOfTag(mods []modules.Module, tagName string) *modules.Module {
+	for i := range mods {
+		if _, ok := mods[i].Version(tagName); ok {
+			return &mods[i]
+		}
+	}
+	return nil
+}
+
+func tagPrefixes(mods []modules.Module) string {
+	var prefixes []string
+	for _, m := range mods {
+		prefixes = append(prefixes, fmt.Sprintf("%q", m.TagPrefix()))
+	}
+	return strings.Join(prefixes, ", ")
+}
+
+// tagName returns the git tag of a version of a module, e.g. v1.2.3 or tools/v0.3.1.
+func tagName(m modules.Module, tg *tag.Tag) string {
+	return m.TagName(tg.String())
+}
+
+// forModule describes a nested module in messages, e.g. " of module tools".
+func forModule(m modules.Module) string {
+	if m.Dir == "." {
+		return ""
+	}
+	return " of module " + m.Dir
+}
+
+// pushedRefs returns the pushed tags that belong to a module.
+func pushedRefs(m modules.Module) []prepush.Ref {
+	var refs []prepush.Ref
+	for _, r := range push.Tags() {
+		if _, ok := m.Version(r.Tag()); ok {
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+// requiredTag returns the lowest tag after the (non-zero) remote tag that the Conventional Commit
+// messages since the remote tag allow, and whether there are such commits. Only commits that touch
+// the module count. When the messages are ignored or can't be read, it's the next patch.
+func requiredTag(fs *errs.Findings, m modules.Module, remoteTag *tag.Tag) (*tag.Tag, bool) {
+	cmd := []string{"git", "log", conventional.LogFormat, "refs/tags/" + tagName(m, remoteTag) + "..HEAD", "--", m.Dir}
+	mods, err := repoModules()
+	if err != nil {
+		return remoteTag.NextPatch(), true
 	}
-	for _, l := range lines {
-		if strings.Contains(l, statusOK) {
-			return nil
+	for _, other := range mods {
+		if other.Dir != m.Dir && (m.Dir == "." || strings.HasPrefix(other.Dir, m.Dir+"/")) {
+			cmd = append(cmd, ":(exclude)"+other.Dir)
 		}
 	}
-	errs.Add(lines...)
-	return errs.Add(
-		"not everything is commited, run:",
-		action.Suggest("git status              # to check what's needed"),
-		action.Suggest("git add $FILE(s)        # to add new files if needed"),
-		action.Suggest("git commit -m $MESSAGE  # to locally commit"))
+	lines, err := run.Exec("reading commit messages since "+tagName(m, remoteTag), cmd)
+	if err != nil {
+		fs.Warn(
+			fmt.Sprintf("can't read the commits since %v to determine the version bump, to fetch the tags, run:", tagName(m, remoteTag)),
+			action.Suggest("git fetch --tags %v", pushRemote()))
+		return remoteTag.NextPatch(), true
+	}
+	msgs := conventional.ParseLog(lines)
+	if len(msgs) == 0 {
+		out.Msg("no commits%v since %v", forModule(m), tagName(m, remoteTag))
+	}
+	if cfg.Conventional == config.ConventionalOff {
+		return remoteTag.NextPatch(), len(msgs) > 0
+	}
+	bump := conventional.Implied(msgs)
+	required := conventional.Next(remoteTag, bump)
+	if bump != conventional.None {
+		out.Msg("the commits since %v imply a %v bump to at least %v", tagName(m, remoteTag), bump, tagName(m, required))
+	}
+	return required, len(msgs) > 0
 }
 
-func goVets() error {
-	_, err := run.Exec("checking go vet on local packages",
-		[]string{"go", "vet", "./..."})
-	return err
+// checkBump verifies that a new tag isn't lower than what the commit messages imply. It's an error
+// when the configuration enforces this, otherwise a warning.
+func checkBump(fs *errs.Findings, m modules.Module, tg, required *tag.Tag) bool {
+	if conventional.Satisfies(tg, required) {
+		return true
+	}
+	sev := errs.Warn
+	if cfg.Conventional == config.ConventionalEnforce {
+		sev = errs.Error
+	}
+	fs.Add(sev,
+		fmt.Sprintf("tag %v is lower than %v, which the Conventional Commit messages imply, to retag, run:", tagName(m, tg), tagName(m, required)),
+		action.Suggest("git tag -d %v", tagName(m, tg)),
+		action.Suggest("git tag -a %v -m %v", tagName(m, required), tagName(m, required)))
+	return false
 }
 
-/* Ouch.. this badly messes up READMEs. Not using.
-func mdUntab() error {
-	_, err := os.Stat("README.md")
+// apiTag returns the next major tag when the exported API has breaking changes since the highest
+// remote release, or nil. Prereleases make no promises about their API, so they aren't compared
+// with, and before v1.0.0 anything may change, so v0 is exempt.
+func apiTag(fs *errs.Findings, m modules.Module) *tag.Tag {
+	release, err := remoteReleaseTag(m)
 	if err != nil {
-		return err
+		fs.Warn(fmt.Sprintf("can't find the remote release tag%v: %v", forModule(m), err))
+		return nil
 	}
-	lines, err := run.Exec("untabifying README.MD using tab2space",
-		[]string{"tab2space", "-lf", "README.md"})
+	if release.IsZero() || release.Major == 0 || m.Path == "" {
+		return nil
+	}
+	breaking, err := apiBreaks(m, release)
 	if err != nil {
-		out.Error("(Not fatal) tab2space failed")
+		fs.Warn(fmt.Sprintf("can't compare the exported API with %v: %v", tagName(m, release), err))
 		return nil
 	}
-	if err = os.WriteFile("README.md", []byte(strings.Join(lines, "\n")), 0644); err != nil {
-		return err
+	if len(breaking) == 0 {
+		out.Msg("no breaking API changes since %v", tagName(m, release))
+		return nil
 	}
-	return nil
+	for _, c := range breaking {
+		f := fs.Info(fmt.Sprintf("breaking API change since %v: %v", tagName(m, release), c))
+		if c.File != "" { // removed identifiers have no location
+			f.At(m.File(c.File), c.Line)
+		}
+	}
+	return release.NextMajor()
 }
-*/
 
-func mdUntab() error {
-	out.Title("untabbing " + readme)
-	b, err := os.ReadFile(readme)
+// apiBreaks compares the exported API at the remote tag, checked out in a temporary worktree,
+// with the API of the working tree, and returns the breaking changes.
+func apiBreaks(m modules.Module, remoteTag *tag.Tag) ([]apicompat.Change, error) {
+	dir, err := os.MkdirTemp("", "gogit-api-")
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if _, err := run.Exec("checking out "+tagName(m, remoteTag)+" in a temporary worktree",
+		[]string{"git", "worktree", "add", "--detach", dir, "refs/tags/" + tagName(m, remoteTag)}); err != nil {
+		return nil, err
+	}
+	defer run.Exec("removing the temporary worktree",
+		[]string{"git", "worktree", "remove", "--force", dir})
+
+	out.Title("comparing the exported API with " + tagName(m, remoteTag))
+	oldAPI, err := apicompat.Load(filepath.Join(dir, m.Dir))
+	if err != nil {
+		return nil, err
+	}
+	newAPI, err := apicompat.Load(m.Dir)
+	if err != nil {
+		return nil, err
 	}
-	untabbed := []string{}
-	active := false
-	changed := false
-	for _, line := range strings.Split(string(b), "\n") {
-		if len(line) == 0 {
-			untabbed = append(untabbed, line)
+	return apicompat.Breaking(apicompat.Compare(oldAPI, newAPI)), nil
+}
+
+// checkAPI verifies that a new tag bumps the major version when the API has breaking changes,
+// i.e. when apiRequired isn't nil.
+func checkAPI(fs *errs.Findings, m modules.Module, tg, apiRequired *tag.Tag) bool {
+	if apiRequired == nil || conventional.Satisfies(tg, apiRequired) {
+		return true
+	}
+	fs.Error(
+		fmt.Sprintf("tag %v doesn't bump the major version, but the exported API has breaking changes, to retag, run:", tagName(m, tg)),
+		action.Suggest("git tag -d %v", tagName(m, tg)),
+		action.Suggest("git tag -a %v -m %v", tagName(m, apiRequired), tagName(m, apiRequired)))
+	return false
+}
+
+// pushedTags validates the pushed tags of a module: they must be well-formed, shouldn't move a
+// tag that the remote already has, must be higher than the highest remote tag, and shouldn't be
+// lower than the required tag (when known), nor keep the major version despite breaking API
+// changes.
+func pushedTags(fs *errs.Findings, m modules.Module, refs []prepush.Ref, remoteTag, required, apiRequired *tag.Tag) {
+	for _, r := range refs {
+		version, _ := m.Version(r.Tag())
+		tg, err := tag.New(version)
+		if err != nil {
+			fs.Error(fmt.Sprintf("pushed tag %q is invalid: %v", r.Tag(), err))
 			continue
 		}
-		if strings.HasPrefix(line, "```") {
-			active = !active
-			untabbed = append(untabbed, line)
+		if !r.IsNew() {
+			fs.Error(fmt.Sprintf("tag %v already exists on %v, published tags shouldn't be moved", r.Tag(), push.Remote))
 			continue
 		}
-		if !active {
-			untabbed = append(untabbed, line)
+		if !remoteTag.IsZero() && !tg.Greater(remoteTag) {
+			fs.Error(fmt.Sprintf("pushed tag %v should indicate a higher version than the remote tag %v", r.Tag(), tagName(m, remoteTag)))
 			continue
 		}
-
-		// We're in a code block. Find the # of leading tabs.
-		nTabs := 0
-		for _, r := range line {
-			if r != '\t' {
-				break
-			}
-			nTabs++
+		if required != nil && !checkBump(fs, m, tg, required) {
+			continue
 		}
-		var leader string
-		for i := 0; i < nTabs; i++ {
-			leader += "    "
-			line = line[1:]
-			changed = true
+		if !checkAPI(fs, m, tg, apiRequired) {
+			continue
 		}
-		untabbed = append(untabbed, leader+line)
-	}
-	if active {
-		return errors.New(readme + ": code block ``` opened, but not closed")
-	}
-	if !changed {
-		return nil
+		fs.Info(fmt.Sprintf("pushed tag %v is valid", r.Tag()))
 	}
+}
 
-	if err := os.Rename(readme, readme+".org"); err != nil {
-		return err
+// pushRemote returns the name of the remote that is pushed to, or "origin" when unknown.
+func pushRemote() string {
+	if push != nil {
+		return push.Remote
 	}
-	// Ensure \n at the end.
-	if len(untabbed) > 0 && untabbed[len(untabbed)-1] != "" {
-		untabbed = append(untabbed, "")
-	}
-	if err := os.WriteFile(readme, []byte(strings.Join(untabbed, "\n")), 0644); err != nil {
-		return fmt.Errorf("failed to ovwerwrite %v: %v, original is in %v.org", readme, readme, err)
-	}
-	if err := os.Remove(readme + ".org"); err != nil {
-		return fmt.Errorf("untabbed %v was created, but backup %v.org cannot be deleted: %v", readme, readme, err)
-	}
-
-	return nil
+	return "origin"
 }
 
-func mdToc() error {
-	out.Title("refreshing table of contents in " + readme)
-	b, err := os.ReadFile(readme)
+// modPath verifies that from v2 on, the module path in go.mod ends in the major version of the
+// local tag, and suggests the go.mod edit and the import rewrites when it doesn't.
+func modPath(fs *errs.Findings) error {
+	out.Title("checking module paths against the major versions")
+	mods, err := repoModules()
 	if err != nil {
 		return err
 	}
-	nTags := 0
-	for _, l := range strings.Split(string(b), "\n") {
-		if strings.HasPrefix(l, tocStart) || strings.HasPrefix(l, tocEnd) {
-			nTags++
-		}
-	}
-	switch nTags {
-	case 0:
-		out.Error(strings.Join([]string{
-			"(Not fatal) " + readme + " has no Table of Contents section",
-			"to have the TOC automatically updated, run:",
-			action.Suggest("go install github.com/kubernetes-sigs/mdtoc@latest"),
-			action.Suggest("add   %v    to "+readme+" (at first column)", tocStart),
-			action.Suggest("add   %v   to "+readme+" (at first column)", tocEnd),
-		}, "\n"))
-		return nil
-	case 2:
-		_, err := run.Exec("refreshing "+readme+" TOC",
-			[]string{"mdtoc", "--inplace", readme})
-		if err != nil {
+	for _, m := range mods {
+		if err := modPathModule(fs, m); err != nil {
 			return err
 		}
-	default:
-		return fmt.Errorf("%v must contain exactly one tag `%v` and one tag `%v`, found: %v", readme, tocStart, tocEnd, nTags)
 	}
-	return nil // to satisfy the signature
+	return nil
 }
 
-func gitTag() error {
-	out.Title("checking git tags")
-	localTag, err := localGitTag()
+func modPathModule(fs *errs.Findings, m modules.Module) error {
+	localTag, err := localGitTag(m)
+	if skipUntagged(fs, m, err) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
-	remoteTag, err := remoteGitTag()
+	current, err := modulePath(m)
 	if err != nil {
 		return err
 	}
-	out.Msg("local tag: %q, remote tag: %q", localTag, remoteTag)
-	ahead, err := localIsAhead()
+	want := modpath.ForMajor(current, localTag.Major)
+	if want == current {
+		out.Msg("module path %q matches tag %v", current, tagName(m, localTag))
+		return nil
+	}
+	line := 0
+	if f, err := gomod.Parse(m.File(modules.ModFile)); err == nil && f.Module != nil {
+		line = f.Module.Syntax.Start.Line
+	}
+	fs.Error(
+		fmt.Sprintf("module path %q doesn't match tag %v, it should be %q, run:", current, tagName(m, localTag), want),
+		action.Suggest("go -C %v mod edit -module %v", m.Dir, want)).At(m.File(modules.ModFile), line)
+	imports, err := modpath.Imports(m.Dir, current)
 	if err != nil {
 		return err
 	}
-	if !remoteTag.IsZero() && !localTag.Greater(remoteTag) && ahead {
-		nextTag := remoteTag.Next()
-		return errors.New(
-			strings.Join([]string{
-				"the local tag should indicate a higher version than the remote one",
-				"increase the local tag first, run:",
-				action.Suggest("# ---- either: increase the tag ID and push ----"),
-				action.Suggest("git tag -a %v -m %v  # or increase major/minor numbers", nextTag, nextTag),
-				action.Suggest("git push"),
-				action.Suggest("git push origin %v", nextTag),
-				"alternatively, to stay on the same tag number, run:",
-				action.Suggest("# ---- or: stay on the same tag ID ----"),
-				action.Suggest("git push --no-verify"),
-			}, "\n"))
+	if len(imports) == 0 {
+		return nil
 	}
-	if !localTag.IsZero() && !localTag.Equal(remoteTag) {
-		out.Msg("local tag %v will need pushing to remote, remember to run:", localTag)
-		out.Msg(action.Suggest("git push origin %v", localTag))
-	} else if localTag.IsZero() {
-		out.Msg("local tag %v is still at zero; to increase to a working set, run:", localTag)
-		out.Msg(action.Suggest("# --- if needed, increase tag to a supported version"))
-		out.Msg(action.Suggest("git tag -a v0.0.1 -m v0.0.1"))
+	var files []string
+	edits := map[string][]string{}
+	for _, imp := range imports {
+		rewritten := imp.Rewrite(current, want)
+		f := m.File(imp.File)
+		fs.Info(fmt.Sprintf("import %q should become %q", imp.Path, rewritten)).At(f, imp.Line)
+		if _, ok := edits[f]; !ok {
+			files = append(files, f)
+		}
+		edits[f] = append(edits[f], fmt.Sprintf(`-e 's|"%v"|"%v"|'`,
+			strings.ReplaceAll(imp.Path, ".", `\.`), rewritten))
+	}
+	var suggestions []string
+	for _, f := range files {
+		suggestions = append(suggestions,
+			action.Suggest("sed -i.bak %v %v && rm %v.bak", strings.Join(edits[f], " "), f, f))
+	}
+	fs.Info(fmt.Sprintf("to rewrite the imports of %v files, run:", len(files)), suggestions...)
+	return nil
+}
+
+func pkgGoDev(fs *errs.Findings) error {
+	mods, err := repoModules()
+	if err != nil {
+		return err
+	}
+	for _, m := range mods {
+		if err := pkgGoDevModule(fs, m); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func pkgGoDev() error {
+func pkgGoDevModule(fs *errs.Findings, m modules.Module) error {
 	// Don't suggest entering on pkg.go.dev if the we're on v0.0.0
-	ltag, err := localGitTag()
+	ltag, err := localGitTag(m)
+	if skipUntagged(fs, m, err) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -426,7 +1757,7 @@ func pkgGoDev() error {
 	}
 
 	// Are we on pkg.go.dev yet?
-	packageName, err := mainPackage()
+	packageName, err := modulePath(m)
 	if err != nil {
 		return err
 	}
@@ -440,29 +1771,29 @@ func pkgGoDev() error {
 	}
 
 	// Suggest adding
-	out.Msg("to add the package on pkg.go.dev:")
-	out.Msg(action.Suggest("goto %v and click the Request button", pkg.URL()))
+	fs.Info(
+		fmt.Sprintf("to add the package %v on pkg.go.dev:", packageName),
+		action.Suggest("goto %v and click the Request button", pkg.URL()))
 	return nil
 }
 
-func localGitTag() (*tag.Tag, error) {
-	if tagLocal != nil {
-		return tagLocal, nil
+// localGitTag returns the highest local tag of a module, cached after first lookup.
+func localGitTag(m modules.Module) (*tag.Tag, error) {
+	if tg, ok := tagsLocal[m.Dir]; ok {
+		return tg, nil
 	}
 	lines, err := run.Exec("checking local git tag",
 		[]string{"git", "tag"})
 	if err != nil {
 		return nil, err
 	}
-	if len(lines) < 1 {
-		return nil, errors.New(strings.Join([]string{
-			"local tag not found, for a first tagging, run:",
-			action.Suggest("git tag -a v0.0.0 -m v0.0.0"),
-		}, "\n"))
-	}
 	tgs := tags.New()
 	for _, l := range lines {
-		if err = tgs.Add(l); err != nil {
+		version, ok := m.Version(l)
+		if !ok {
+			continue
+		}
+		if err = tgs.Add(version); err != nil {
 			return nil, errors.New(strings.Join([]string{
 				err.Error(),
 				"manually correct using:",
@@ -470,51 +1801,104 @@ func localGitTag() (*tag.Tag, error) {
 			}, "\n"))
 		}
 	}
-	tagLocal = tgs.Highest()
-	return tagLocal, nil
+	if !tgs.HasTags() {
+		return nil, untaggedError{m}
+	}
+	tagsLocal[m.Dir] = tgs.Highest()
+	return tagsLocal[m.Dir], nil
+}
+
+// untaggedError is returned by localGitTag for a module without local tags.
+type untaggedError struct {
+	m modules.Module
+}
+
+func (e untaggedError) Error() string {
+	first := e.m.TagName("v0.0.0")
+	return strings.Join([]string{
+		fmt.Sprintf("local tag%v not found, for a first tagging, run:", forModule(e.m)),
+		action.Fix("git tag -a %v -m %v", first, first),
+	}, "\n")
 }
 
-func remoteGitTag() (*tag.Tag, error) {
-	if tagRemote != nil {
-		return tagRemote, nil
+// skipUntagged reports whether the tag checks of a module are skipped because err says it has no
+// local tags. That is a warning for nested modules, e.g. an internal tools module that is never
+// tagged shouldn't fail the checks of the others; the top level module must be tagged. The
+// warning is given once, by the first check that skips the module.
+func skipUntagged(fs *errs.Findings, m modules.Module, err error) bool {
+	var u untaggedError
+	if m.Dir == "." || !errors.As(err, &u) {
+		return false
+	}
+	if untaggedWarned[m.Dir] {
+		return true
+	}
+	untaggedWarned[m.Dir] = true
+	first := m.TagName("v0.0.0")
+	fs.Warn(
+		fmt.Sprintf("local tag%v not found, skipping its tag checks, for a first tagging, run:", forModule(m)),
+		action.Suggest("git tag -a %v -m %v", first, first)).At(m.File(modules.ModFile), 0)
+	return true
+}
+
+// remoteGitTag returns the highest remote tag of a module, or nil when there is none.
+func remoteGitTag(m modules.Module) (*tag.Tag, error) {
+	tgs, err := remoteTags(m)
+	if err != nil || !tgs.HasTags() {
+		return nil, err
 	}
-	lines, err := run.Exec("checking remote git tag",
-		[]string{"git", "ls-remote", "--tags"})
+	return tgs.Highest(), nil
+}
+
+// remoteReleaseTag returns the highest remote tag of a module that isn't a prerelease, or nil when
+// there is none.
+func remoteReleaseTag(m modules.Module) (*tag.Tag, error) {
+	tgs, err := remoteTags(m)
 	if err != nil {
 		return nil, err
 	}
-	if len(lines) < 2 {
-		return nil, nil
+	if tg := tgs.HighestRelease(); !tg.IsZero() {
+		return tg, nil
+	}
+	return nil, nil
+}
+
+// remoteTags returns the remote tags of a module, cached after first lookup.
+func remoteTags(m modules.Module) (*tags.Tags, error) {
+	if tgs, ok := tagsRemote[m.Dir]; ok {
+		return tgs, nil
+	}
+	cmd := []string{"git", "ls-remote", "--tags"}
+	if push != nil {
+		cmd = append(cmd, push.Remote)
+	}
+	lines, err := run.Exec("checking remote git tag", cmd)
+	if err != nil {
+		return nil, err
 	}
 	tgs := tags.New()
 	for _, l := range lines {
-		if !strings.Contains(l, remoteTagFormat) {
+		_, ref, found := strings.Cut(l, remoteTagFormat)
+		if !found {
 			continue
 		}
-		if t := tag.TagRe.FindString(l); t != "" {
-			if err := tgs.Add(t); err != nil {
-				errs := []string{
-					"remote tags could not be parsed, for a first tagging, run:",
-					action.Suggest("git tag -a v0.0.0 -m v0.0.0"),
-					"output of command was:",
-				}
-				errs = append(errs, lines...)
-				return nil, errors.New(strings.Join(errs, "\n"))
-			}
+		version, ok := m.Version(strings.TrimSuffix(ref, "^{}"))
+		if !ok || !tag.TagRe.MatchString(version) {
+			continue
+		}
+		if err := tgs.Add(version); err != nil {
+			continue // not a version, e.g. "latest"
 		}
 	}
-	if !tgs.HasTags() {
-		return nil, nil
-	}
-	tagRemote = tgs.Highest()
-	return tagRemote, nil
+	tagsRemote[m.Dir] = tgs
+	return tgs, nil
 }
 
-func haveRemote() error {
+func haveRemote(fs *errs.Findings) error {
 	lines, err := run.Exec("checking for remote repositories",
 		[]string{"git", "remote"})
 	if err != nil {
-		return errs.Add(err.Error())
+		return err
 	}
 	if len(lines) > 0 {
 		for _, l := range lines {
@@ -522,62 +1906,380 @@ func haveRemote() error {
 		}
 		return nil
 	}
-	var suggestions []string
 	repo := path.Base(gitTop)
 	if strings.Contains(gitTop, "github.com") {
 		_, after, found := strings.Cut(gitTop, "github.com")
 		if !found || after == "" {
-			return errs.Add(fmt.Sprintf("internal jam: failed to parse %q (after:%v, found:%v)", gitTop, after, found))
+			return fmt.Errorf("internal jam: failed to parse %q (after:%v, found:%v)", gitTop, after, found)
 		}
 		githubURI := fmt.Sprintf("https://github.com/%v", after)
-		suggestions = append(suggestions,
-			fmt.Sprintf("on github.com add the repository %v, and then:", repo),
-			fmt.Sprintf("git remote add origin %v.git", githubURI))
+		fs.Error(
+			fmt.Sprintf("no remote repository is configured, on github.com add the repository %v, and then:", repo),
+			action.Suggest("git remote add origin %v.git", githubURI))
 	} else {
-		suggestions = []string{"git remote add $REMOTE"}
+		fs.Error(
+			"no remote repository is configured, run:",
+			action.Suggest("git remote add $REMOTE"))
+	}
+	return nil
+}
+
+// restage adds the files that checks modified (e.g. the untabbed README) to the commit that's
+// being made, when they had no unstaged changes before. Files that did can't be added without
+// committing those changes too, which fails the check.
+func restage(fs *errs.Findings) error {
+	out.Title("checking for files that checks modified")
+	if unstagedBefore == nil {
+		return nil
+	}
+	after, err := unstagedFiles()
+	if err != nil {
+		return err
+	}
+	var modified []string
+	for f, hash := range after {
+		before, ok := unstagedBefore[f]
+		switch {
+		case !ok:
+			modified = append(modified, f)
+		case before != hash:
+			fs.Error("modified by gogit, but it also has unstaged changes, so it can't be "+
+				"re-staged; review the changes, git add what belongs to the commit, and commit again").At(f, 0)
+		}
+	}
+	if len(modified) == 0 {
+		return nil
+	}
+	sort.Strings(modified)
+	var quoted []string
+	for _, f := range modified {
+		quoted = append(quoted, shellQuote(f))
+	}
+	if _, err := run.Shell("re-staging modified files", "git add -- "+strings.Join(quoted, " ")); err != nil {
+		return err
+	}
+	out.Msg("re-staged %v", strings.Join(modified, ", "))
+	return nil
+}
+
+// unstagedFiles returns the files whose working tree differs from the index, with a hash of their
+// content ("" when deleted).
+func unstagedFiles() (map[string]string, error) {
+	lines, err := run.Shell("finding unstaged changes", "git -c core.quotePath=false diff --name-only --no-renames")
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	for _, f := range lines {
+		b, err := os.ReadFile(f)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			files[f] = ""
+		case err != nil:
+			return nil, err
+		default:
+			files[f] = fmt.Sprintf("%x", sha256.Sum256(b))
+		}
+	}
+	return files, nil
+}
+
+// stashUnstaged stashes unstaged changes and untracked files, so that checks see what's staged.
+func stashUnstaged() error {
+	lines, err := run.Exec("checking for unstaged changes", []string{"git", "status", "--porcelain"})
+	if err != nil {
+		return err
+	}
+	unstaged := false
+	for _, l := range lines {
+		if strings.HasPrefix(l, "??") || (len(l) > 1 && l[1] != ' ') {
+			unstaged = true
+		}
+	}
+	if !unstaged {
+		return nil
+	}
+	if _, err := run.Shell("stashing unstaged changes",
+		"git stash push --keep-index --include-untracked --quiet --message 'gogit: unstaged changes'"); err != nil {
+		return err
+	}
+	stashed = true
+	forgetCaches()
+	return nil
+}
+
+// restoreUnstaged restores what stashUnstaged stashed: the files with unstaged changes and the
+// untracked files are restored from the stash, without touching the index, which checks may have
+// updated. When that fails, the changes stay in the stash and the user is told how to get them back.
+func restoreUnstaged() {
+	if !stashed {
+		return
+	}
+	stashed = false
+	if err := restoreStash(); err != nil {
+		out.Error(fmt.Sprintf("unstaged changes couldn't be restored: %v", err),
+			"they are kept in the stash, to restore, run:",
+			action.Suggest("git stash pop"))
+	}
+}
+
+func restoreStash() error {
+	// Checks may have modified files that have unstaged changes (e.g. untabbed a README), restage
+	// refuses those. Their changes are merged into the restored files, rather than overwritten.
+	rewrites, err := stashedRewrites()
+	if err != nil {
+		return err
+	}
+	for _, restore := range []struct {
+		source string
+		list   func() ([]string, error)
+	}{
+		{"stash@{0}", stashedChanges},
+		{"stash@{0}^3", stashedUntracked},
+	} {
+		files, err := restore.list()
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			continue
+		}
+		var quoted []string
+		for _, f := range files {
+			quoted = append(quoted, shellQuote(f))
+		}
+		if _, err := run.Shell("restoring unstaged changes",
+			fmt.Sprintf("git restore --source='%v' --worktree -- %v", restore.source, strings.Join(quoted, " "))); err != nil {
+			return err
+		}
+	}
+	for _, f := range slices.Sorted(maps.Keys(rewrites)) {
+		if err := mergeRewrite(f, rewrites[f]); err != nil {
+			return err
+		}
+	}
+	_, err = run.Shell("dropping the stash", "git stash drop --quiet")
+	return err
+}
+
+// stashedChanges returns the tracked files that have unstaged changes in the stash. The stash
+// commit holds the working tree, its second parent the index.
+func stashedChanges() ([]string, error) {
+	return run.Exec("listing stashed files",
+		[]string{"git", "-c", "core.quotePath=false", "diff", "--name-only", "--no-renames", "stash@{0}^2", "stash@{0}"})
+}
+
+// stashedUntracked returns the untracked files in the stash, which its third parent holds, if any.
+func stashedUntracked() ([]string, error) {
+	if _, err := run.Shell("checking stash for untracked files",
+		"git rev-parse --quiet --verify 'stash@{0}^3' >/dev/null"); err != nil {
+		return nil, nil // no untracked files
+	}
+	return run.Exec("listing stashed untracked files",
+		[]string{"git", "-c", "core.quotePath=false", "ls-tree", "-r", "--name-only", "stash@{0}^3"})
+}
+
+// stashedRewrites returns the contents of the files with stashed changes that checks modified.
+func stashedRewrites() (map[string][]byte, error) {
+	files, err := stashedChanges()
+	if err != nil || len(files) == 0 {
+		return nil, err
 	}
-	errs.Add("no remote repository is configured")
-	return errs.Add(suggestions...)
+	modified, err := run.Shell("finding files that checks modified",
+		"git -c core.quotePath=false diff --name-only --no-renames")
+	if err != nil {
+		return nil, err
+	}
+	rewrites := map[string][]byte{}
+	for _, f := range modified {
+		if !slices.Contains(files, f) {
+			continue
+		}
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		rewrites[f] = b
+	}
+	return rewrites, nil
+}
+
+// mergeRewrite merges what a check made of the staged version of a file into the restored file
+// with unstaged changes. Conflicts are marked in the file.
+func mergeRewrite(f string, rewrite []byte) error {
+	if _, err := os.Stat(f); errors.Is(err, os.ErrNotExist) {
+		out.Msg("%v was deleted, not merging the changes of the checks", f)
+		return nil
+	}
+	dir, err := os.MkdirTemp("", "gogit-merge-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	base, checked := filepath.Join(dir, "staged"), filepath.Join(dir, "gogit")
+	if err := os.WriteFile(checked, rewrite, 0644); err != nil {
+		return err
+	}
+	if _, err := run.Shell("reading the staged "+f,
+		fmt.Sprintf("git cat-file blob %v > %v", shellQuote("stash@{0}^2:"+f), shellQuote(base))); err != nil {
+		return err
+	}
+	_, err = run.Shell("merging the changes of the checks into "+f,
+		fmt.Sprintf("git merge-file -L unstaged -L staged -L gogit %v %v %v", shellQuote(f), shellQuote(base), shellQuote(checked)))
+	// git merge-file exits with the number of conflicts, or a negative status on errors.
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() > 0 && exit.ExitCode() < 128 {
+		out.Error(fmt.Sprintf("%v: the changes of the checks conflict with the unstaged changes, resolve the conflict markers", f))
+		return nil
+	}
+	return err
+}
+
+// self returns the quoted path of the running gogit binary, for suggestions that run gogit: a
+// gogit on the PATH may be another version, or missing.
+func self() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return "gogit"
+	}
+	return shellQuote(exe)
+}
+
+// shellQuote quotes a string for `sh -c`.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
-func mainPackage() (name string, err error) {
-	if mainPackageName != "" {
-		return mainPackageName, nil
+// packagesOf returns the package patterns of a module that tests and analyzers run on: all
+// packages, or in staged mode, the packages that staged files affect and the packages that import
+// those. In staged mode, none may be affected.
+func packagesOf(m modules.Module) ([]string, error) {
+	if !staged {
+		return []string{"./..."}, nil
 	}
-	out.Title("fetching main package name")
-	b, err := os.ReadFile("go.mod")
+	if pkgs, ok := stagedPkgs[m.Dir]; ok {
+		return pkgs, nil
+	}
+	mods, err := repoModules()
 	if err != nil {
-		return "", fmt.Errorf("can't read `go.mod`: %v", err)
+		return nil, err
 	}
-	lines := strings.Split(string(b), "\n")
-	if len(lines) < 1 {
-		return "", fmt.Errorf("`go.mod` must contain at least 1 line")
+	files, err := run.Exec("finding staged files",
+		[]string{"git", "-c", "core.quotePath=false", "diff", "--cached", "--name-only", "--no-renames"})
+	if err != nil {
+		return nil, err
 	}
-	parts := strings.Split(lines[0], " ")
-	if len(parts) != 2 || parts[0] != "module" {
-		return "", fmt.Errorf("`go.mod` has an incorrect first line, expected `module so-and-so`, got %q", lines[0])
+	var own []string
+	for _, f := range files {
+		if fm, ok := modules.Of(mods, f); ok && fm.Dir == m.Dir {
+			own = append(own, m.Rel(f))
+		}
 	}
-	supported := false
-	for _, url := range supportedRemoteRepos {
-		if strings.HasPrefix(parts[1], url) {
-			supported = true
-			break
+	var pkgs []string
+	if len(own) > 0 {
+		lines, err := run.Exec("listing packages"+forModule(m),
+			[]string{"go", "-C", m.Dir, "list", "-e", "-f", affected.ListFormat, "./..."})
+		if err != nil {
+			return nil, err
+		}
+		g := affected.ParseList(lines)
+		changed, all := g.Packages(m.Path, own)
+		if all {
+			pkgs = []string{"./..."}
+		} else {
+			pkgs = g.ReverseDeps(changed)
 		}
 	}
-	if !supported {
-		return "", fmt.Errorf("`go.mod`: remote repo %q not supported, must start with %v",
-			parts[1], strings.Join(supportedRemoteRepos, " or "))
+	if len(pkgs) == 0 {
+		out.Msg("no packages%v are affected by staged changes", forModule(m))
+	} else {
+		out.Msg("packages%v affected by staged changes: %v", forModule(m), strings.Join(pkgs, " "))
+	}
+	stagedPkgs[m.Dir] = pkgs
+	return pkgs, nil
+}
+
+// repoModules returns the Go modules of the repository, cached after first lookup.
+func repoModules() ([]modules.Module, error) {
+	if repoMods != nil {
+		return repoMods, nil
+	}
+	out.Title("finding go modules")
+	mods, err := modules.Discover(".")
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mods {
+		out.Msg("module %q in %v", m.Path, m.Dir)
+	}
+	repoMods = append([]modules.Module{}, mods...)
+	return repoMods, nil
+}
+
+// modulePath returns the path of a module, which must be on a supported remote repository.
+func modulePath(m modules.Module) (string, error) {
+	if m.Path == "" {
+		return "", fmt.Errorf("can't read `%v`", m.File(modules.ModFile))
+	}
+	for _, url := range cfg.RemoteRepos {
+		if strings.HasPrefix(m.Path, url) {
+			return m.Path, nil
+		}
 	}
-	mainPackageName = parts[1]
-	out.Msg("main package name is %q", mainPackageName)
-	return mainPackageName, nil
+	return "", fmt.Errorf("`%v`: remote repo %q not supported, must start with %v",
+		m.File(modules.ModFile), m.Path, strings.Join(cfg.RemoteRepos, " or "))
 }
 
+// localIsAhead returns whether there are local commits to push, cached after first lookup. As the
+// pre-push hook, it checks the refs that are pushed to the remote that git names.
 func localIsAhead() (ahead bool, err error) {
 	if localAheadCached {
 		return localAheadStatus, nil
 	}
 	out.Title("checking whether local repo is ahead of remote")
+	if push != nil {
+		ahead, err = pushIsAhead()
+	} else {
+		ahead, err = branchIsAhead()
+	}
+	if err != nil {
+		return false, err
+	}
+	localAheadCached = true
+	localAheadStatus = ahead
+	return localAheadStatus, nil
+}
+
+// pushIsAhead returns whether the pre-push hook pushes commits that the remote doesn't have yet:
+// a new branch, or a branch whose remote commit lacks commits of the local one.
+func pushIsAhead() (bool, error) {
+	for _, r := range push.Refs {
+		if r.Tag() != "" || r.IsDelete() || r.LocalSHA == r.RemoteSHA {
+			continue
+		}
+		if r.IsNew() {
+			out.Msg("%v is new on %v", r.RemoteRef, push.Remote)
+			return true, nil
+		}
+		lines, err := run.Capture("counting commits to push",
+			[]string{"git", "rev-list", "--count", r.RemoteSHA + ".." + r.LocalSHA})
+		if err != nil {
+			// The remote commit isn't known locally, so the remote has commits that weren't
+			// fetched, and the local one differs.
+			out.Msg("%v on %v differs from %v", r.RemoteRef, push.Remote, r.LocalRef)
+			return true, nil
+		}
+		if len(lines) == 1 && lines[0] != "0" {
+			out.Msg("%v is %v commit(s) ahead of %v on %v", r.LocalRef, lines[0], r.RemoteRef, push.Remote)
+			return true, nil
+		}
+	}
+	out.Msg("no commits to push to %v", push.Remote)
+	return false, nil
+}
+
+// branchIsAhead returns whether the current branch is ahead of its upstream.
+func branchIsAhead() (bool, error) {
 	lines, err := run.Exec("checking local status", []string{"git", "status", "-uno"})
 	if err != nil {
 		return false, err
@@ -585,15 +2287,11 @@ func localIsAhead() (ahead bool, err error) {
 	for _, line := range lines {
 		if strings.HasPrefix(line, localIsAheadStr) {
 			out.Msg("local repository is ahead of remote")
-			localAheadCached = true
-			localAheadStatus = true
-			return localAheadStatus, nil
+			return true, nil
 		}
 		if strings.HasPrefix(line, localIsUpToDateStr) {
 			out.Msg("local repository is up to date with remote")
-			localAheadCached = true
-			localAheadStatus = false
-			return localAheadStatus, nil
+			return false, nil
 		}
 	}
 	return false, fmt.Errorf("cannot determine whether the local repo is ahead or not")
@@ -1,7 +1,625 @@
 package main
 
-import "testing"
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
 
-func TestAll(T *testing.T) {
-	// TODO: add tests
+	"github.com/KarelKubat/gogit/config"
+	"github.com/KarelKubat/gogit/errs"
+	"github.com/KarelKubat/gogit/modules"
+	"github.com/KarelKubat/gogit/out"
+	"github.com/KarelKubat/gogit/prepush"
+	"github.com/KarelKubat/gogit/report"
+)
+
+// TestMain runs gogit itself when a test sets GOGIT_TEST_MAIN: fixes invoke the running binary
+// (see self), which in tests is the test binary.
+func TestMain(m *testing.M) {
+	if os.Getenv("GOGIT_TEST_MAIN") != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// newRepo creates a git repository with a first commit of files, and makes it the current
+// directory for the duration of the test.
+func newRepo(t *testing.T, files map[string]string) {
+	t.Helper()
+	out.Record()
+	t.Chdir(t.TempDir())
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	git(t, "init", "--quiet")
+	git(t, "config", "user.name", "gogit")
+	git(t, "config", "user.email", "gogit@example.com")
+	for name, content := range files {
+		write(t, name, content)
+	}
+	git(t, "add", "-A")
+	git(t, "commit", "--quiet", "--no-verify", "-m", "initial")
+	reset()
+	t.Cleanup(reset)
+}
+
+// reset drops the state that a test may have left behind.
+func reset() {
+	forgetCaches()
+	staged, stashed, unstagedBefore = false, false, nil
+	cfg, push = config.New(), nil
+}
+
+// addRemote adds a bare repository as remote origin, and pushes the current branch and the tags
+// to it.
+func addRemote(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	git(t, "init", "--quiet", "--bare", dir)
+	git(t, "remote", "add", "origin", dir)
+	git(t, "push", "--quiet", "--no-verify", "--set-upstream", "origin", "HEAD", "--tags")
+	forgetCaches()
+}
+
+// commit commits a change to a file with a message.
+func commit(t *testing.T, name, content, msg string) {
+	t.Helper()
+	write(t, name, content)
+	git(t, "add", name)
+	git(t, "commit", "--quiet", "--no-verify", "-m", msg)
+	forgetCaches()
+}
+
+func git(t *testing.T, args ...string) string {
+	t.Helper()
+	b, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", strings.Join(args, " "), err, b)
+	}
+	return string(b)
+}
+
+func write(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestStashUnstagedHunks(t *testing.T) {
+	newRepo(t, map[string]string{"a.txt": "1\n2\n"})
+	write(t, "a.txt", "1\n2\n3\n")
+	git(t, "add", "a.txt")
+	write(t, "a.txt", "0\n1\n2\n3\n")
+
+	if err := stashUnstaged(); err != nil {
+		t.Fatalf("stashUnstaged() = %v, want nil error", err)
+	}
+	if !stashed {
+		t.Fatal("stashUnstaged() didn't stash the unstaged hunk")
+	}
+	if got := read(t, "a.txt"); got != "1\n2\n3\n" {
+		t.Errorf("while stashed, a.txt = %q, want the staged version", got)
+	}
+	restoreUnstaged()
+	if got := read(t, "a.txt"); got != "0\n1\n2\n3\n" {
+		t.Errorf("after restoring, a.txt = %q, want the unstaged version", got)
+	}
+	if got := git(t, "diff", "--cached", "--name-only"); got != "a.txt\n" {
+		t.Errorf("after restoring, staged files = %q, want a.txt", got)
+	}
+	if got := git(t, "stash", "list"); got != "" {
+		t.Errorf("after restoring, stash list = %q, want it empty", got)
+	}
+}
+
+func TestStashUntracked(t *testing.T) {
+	newRepo(t, map[string]string{"a.txt": "1\n"})
+	write(t, "a.txt", "2\n")
+	git(t, "add", "a.txt")
+	write(t, "dir/new.txt", "new\n")
+
+	if err := stashUnstaged(); err != nil {
+		t.Fatalf("stashUnstaged() = %v, want nil error", err)
+	}
+	if _, err := os.Stat("dir/new.txt"); !os.IsNotExist(err) {
+		t.Errorf("while stashed, dir/new.txt exists, want it stashed")
+	}
+	restoreUnstaged()
+	if got := read(t, "dir/new.txt"); got != "new\n" {
+		t.Errorf("after restoring, dir/new.txt = %q, want %q", got, "new\n")
+	}
+	if got := git(t, "status", "--porcelain"); got != "M  a.txt\n?? dir/\n" {
+		t.Errorf("after restoring, status = %q, want a.txt staged and dir/ untracked", got)
+	}
+}
+
+func TestStashNothing(t *testing.T) {
+	newRepo(t, map[string]string{"a.txt": "1\n"})
+	write(t, "a.txt", "2\n")
+	git(t, "add", "a.txt")
+
+	if err := stashUnstaged(); err != nil {
+		t.Fatalf("stashUnstaged() = %v, want nil error", err)
+	}
+	if stashed {
+		t.Error("stashUnstaged() stashed, but there are no unstaged changes")
+	}
+}
+
+// TestRestoreAfterFailingCheck modifies a staged file that has unstaged hunks while stashed, as
+// e.g. mduntab does: restage fails, and restoring keeps both the unstaged hunks and the changes of
+// the check.
+func TestRestoreAfterFailingCheck(t *testing.T) {
+	for _, test := range []struct {
+		unstaged string
+		want     string
+	}{
+		{
+			unstaged: "# T\n\n\tcode\n\nend, unstaged\n",
+			want:     "# T\n\n    code\n\nend, unstaged\n",
+		},
+		{
+			unstaged: "# T\n\n\tother code\n\nend\n",
+			want:     "# T\n\n<<<<<<< unstaged\n\tother code\n=======\n    code\n>>>>>>> gogit\n\nend\n",
+		},
+	} {
+		newRepo(t, map[string]string{"README.md": "# T\n\ncode\n\nend\n", "b.txt": "1\n"})
+		write(t, "README.md", "# T\n\n\tcode\n\nend\n")
+		write(t, "b.txt", "2\n")
+		git(t, "add", "README.md", "b.txt")
+		write(t, "README.md", test.unstaged)
+
+		before, err := unstagedFiles()
+		if err != nil {
+			t.Fatalf("unstagedFiles() = _,%v, want nil error", err)
+		}
+		unstagedBefore = before
+		if err := stashUnstaged(); err != nil {
+			t.Fatalf("stashUnstaged() = %v, want nil error", err)
+		}
+		write(t, "README.md", "# T\n\n    code\n\nend\n")
+		fs := errs.New("restage")
+		if err := restage(fs); err != nil {
+			t.Fatalf("restage() = %v, want nil error", err)
+		}
+		if !fs.Failed() {
+			t.Errorf("restage() re-staged a file with unstaged changes, want it to fail")
+		}
+		restoreUnstaged()
+		if got := read(t, "README.md"); got != test.want {
+			t.Errorf("after restoring %q, README.md = %q, want %q", test.unstaged, got, test.want)
+		}
+		if got := git(t, "show", ":README.md"); got != "# T\n\n\tcode\n\nend\n" {
+			t.Errorf("after restoring, staged README.md = %q, want it unchanged", got)
+		}
+		if got := git(t, "stash", "list"); got != "" {
+			t.Errorf("after restoring, stash list = %q, want it empty", got)
+		}
+	}
+}
+
+func TestUnstagedFiles(t *testing.T) {
+	newRepo(t, map[string]string{"a.txt": "1\n", "b.txt": "1\n", "c.txt": "1\n"})
+	write(t, "a.txt", "2\n")
+	if err := os.Remove("b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	write(t, "c.txt", "2\n")
+	git(t, "add", "c.txt")
+	write(t, "untracked.txt", "1\n")
+
+	got, err := unstagedFiles()
+	if err != nil {
+		t.Fatalf("unstagedFiles() = _,%v, want nil error", err)
+	}
+	want := map[string]string{
+		"a.txt": fmt.Sprintf("%x", sha256.Sum256([]byte("2\n"))),
+		"b.txt": "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unstagedFiles() = %v, want %v", got, want)
+	}
+}
+
+func TestPackagesOf(t *testing.T) {
+	newRepo(t, map[string]string{
+		"go.mod":   "module example.com/m\n\ngo 1.22\n",
+		"a/a.go":   "package a\n\nconst A = 1\n",
+		"b/b.go":   "package b\n\nimport \"example.com/m/a\"\n\nconst B = a.A\n",
+		"c/c.go":   "package c\n",
+		"README":   "m\n",
+		"t/go.mod": "module example.com/m/t\n\ngo 1.22\n",
+		"t/t.go":   "package t\n",
+	})
+	mods, err := repoModules()
+	if err != nil {
+		t.Fatalf("repoModules() = _,%v, want nil error", err)
+	}
+	top, nested := mods[0], mods[1]
+
+	if got, _ := packagesOf(top); !reflect.DeepEqual(got, []string{"./..."}) {
+		t.Errorf("packagesOf(%v) = %v, want all packages when not in staged mode", top.Dir, got)
+	}
+	staged = true
+	for _, test := range []struct {
+		files []string
+		m     modules.Module
+		want  []string
+	}{
+		{
+			files: []string{"a/a.go"},
+			m:     top,
+			want:  []string{"example.com/m/a", "example.com/m/b"},
+		},
+		{
+			files: []string{"c/c.go", "README"},
+			m:     top,
+			want:  []string{"example.com/m/c"},
+		},
+		{
+			files: []string{"go.mod"},
+			m:     top,
+			want:  []string{"./..."},
+		},
+		{
+			files: []string{"a/a.go"},
+			m:     nested,
+			want:  nil,
+		},
+	} {
+		for _, f := range test.files {
+			write(t, f, read(t, f)+"\n")
+		}
+		git(t, append([]string{"add"}, test.files...)...)
+		forgetCaches()
+		if got, err := packagesOf(test.m); err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("packagesOf(%v) with %v staged = %v,%v, want %v,nil", test.m.Dir, test.files, got, err, test.want)
+		}
+		git(t, "reset", "--quiet", "--hard")
+	}
+}
+
+func TestGoFiles(t *testing.T) {
+	newRepo(t, map[string]string{
+		"a.go":                 "package a\n",
+		"a_test.go":            "package a\n",
+		"b/b.go":               "package b\n",
+		"b/testdata/broken.go": "package broken(\n",
+		"vendor/v/v.go":        "package v\n",
+		"_old/o.go":            "package o\n",
+		".hidden/h.go":         "package h\n",
+	})
+	got, err := goFiles()
+	if err != nil {
+		t.Fatalf("goFiles() = _,%v, want nil error", err)
+	}
+	if want := []string{"a.go", "a_test.go", "b/b.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("goFiles() = %v, want %v", got, want)
+	}
+}
+
+func TestFix(t *testing.T) {
+	newRepo(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.22\n\nreplace example.com/x => ../x\n",
+		"a.go":   "package a\n",
+	})
+	t.Setenv("GOGIT_TEST_MAIN", "1")
+	*fixFlag = true
+	rep = report.New("pre-commit")
+	t.Cleanup(func() {
+		*fixFlag = false
+		rep = report.New("")
+	})
+	write(t, "a.go", "package a\nfunc  F() {}\n")
+	git(t, "add", "a.go")
+	before, err := unstagedFiles()
+	if err != nil {
+		t.Fatalf("unstagedFiles() = _,%v, want nil error", err)
+	}
+	unstagedBefore = before
+
+	// The safe suggestion formats a.go, the re-run passes, and restage adds it to the commit.
+	if fs := runCheck("gofmt"); fs.Failed() {
+		t.Errorf("runCheck(gofmt) with --fix failed: %+v", fs.List)
+	}
+	if fs := runCheck("restage"); fs.Failed() {
+		t.Errorf("runCheck(restage) failed: %+v", fs.List)
+	}
+	if got, want := git(t, "show", ":a.go"), "package a\n\nfunc F() {}\n"; got != want {
+		t.Errorf("after fixing, staged a.go = %q, want %q", got, want)
+	}
+	if got := git(t, "diff", "--name-only"); got != "" {
+		t.Errorf("after fixing, unstaged files = %q, want none", got)
+	}
+	if got := rep.Checks[0].Suggestions; len(got) != 0 {
+		t.Errorf("after fixing, gofmt suggestions = %v, want them rewound", got)
+	}
+
+	// The unsafe suggestion is only shown, and the check still fails.
+	if fs := runCheck("replaces"); !fs.Failed() {
+		t.Errorf("runCheck(replaces) with --fix passed, want the unsafe suggestion not to run")
+	}
+	if got := read(t, "go.mod"); !strings.Contains(got, "replace example.com/x => ../x") {
+		t.Errorf("after --fix, go.mod = %q, want the replace directive kept", got)
+	}
+	want := []report.Suggestion{{Command: "go -C . mod edit -dropreplace=example.com/x", Safe: false}}
+	if got := rep.Checks[2].Suggestions; !reflect.DeepEqual(got, want) {
+		t.Errorf("replaces suggestions = %+v, want %+v", got, want)
+	}
+}
+
+func TestConventionalModes(t *testing.T) {
+	const underBumped = "is lower than"
+	for _, test := range []struct {
+		msg, localTag string
+		mode          string
+		wantSev       errs.Severity // of the under-bump finding, Info when there is none
+	}{
+		{msg: "feat: add", localTag: "v1.0.1", mode: config.ConventionalEnforce, wantSev: errs.Error},
+		{msg: "feat: add", localTag: "v1.0.1", mode: config.ConventionalSuggest, wantSev: errs.Warn},
+		{msg: "feat: add", localTag: "v1.0.1", mode: config.ConventionalOff, wantSev: errs.Info},
+		{msg: "feat: add", localTag: "v1.1.0", mode: config.ConventionalEnforce, wantSev: errs.Info},
+		{msg: "fix: repair", localTag: "v1.0.1", mode: config.ConventionalEnforce, wantSev: errs.Info},
+		{msg: "feat!: drop", localTag: "v1.1.0", mode: config.ConventionalEnforce, wantSev: errs.Error},
+		{msg: "feat!: drop", localTag: "v1.1.0", mode: config.ConventionalSuggest, wantSev: errs.Warn},
+		{msg: "feat!: drop", localTag: "v1.1.0", mode: config.ConventionalOff, wantSev: errs.Info},
+		{msg: "feat!: drop", localTag: "v2.0.0", mode: config.ConventionalEnforce, wantSev: errs.Info},
+	} {
+		newRepo(t, map[string]string{"go.mod": "module github.com/x/m\n\ngo 1.22\n"})
+		git(t, "tag", "-a", "v1.0.0", "-m", "v1.0.0")
+		addRemote(t)
+		commit(t, "README.md", "m\n", test.msg)
+		git(t, "tag", "-a", test.localTag, "-m", test.localTag)
+		cfg.Conventional = test.mode
+
+		fs := errs.New("gittag")
+		if err := gitTag(fs); err != nil {
+			t.Fatalf("gitTag() = %v, want nil error", err)
+		}
+		var gotSev errs.Severity = errs.Info
+		for _, f := range fs.List {
+			if strings.Contains(f.Msg, underBumped) {
+				gotSev = f.Severity
+			}
+		}
+		if gotSev != test.wantSev || fs.Failed() != (test.wantSev == errs.Error) {
+			t.Errorf("%q tagged %v in mode %v: under-bump severity %v, failed %v, want %v; findings %+v",
+				test.msg, test.localTag, test.mode, gotSev, fs.Failed(), test.wantSev, fs.List)
+		}
+	}
+}
+
+func TestPushIsAhead(t *testing.T) {
+	newRepo(t, map[string]string{"a.txt": "1\n"})
+	addRemote(t)
+	pushed := strings.TrimSpace(git(t, "rev-parse", "HEAD"))
+	commit(t, "a.txt", "2\n", "fix: two")
+	head := strings.TrimSpace(git(t, "rev-parse", "HEAD"))
+	const zero = "0000000000000000000000000000000000000000"
+	unknown := strings.Repeat("1234abcd", 5)
+
+	for _, test := range []struct {
+		desc string
+		refs []prepush.Ref
+		want bool
+	}{
+		{
+			desc: "new branch",
+			refs: []prepush.Ref{{LocalRef: "refs/heads/new", LocalSHA: head, RemoteRef: "refs/heads/new", RemoteSHA: zero}},
+			want: true,
+		},
+		{
+			desc: "branch with a commit to push",
+			refs: []prepush.Ref{{LocalRef: "refs/heads/main", LocalSHA: head, RemoteRef: "refs/heads/main", RemoteSHA: pushed}},
+			want: true,
+		},
+		{
+			desc: "up to date branch",
+			refs: []prepush.Ref{{LocalRef: "refs/heads/main", LocalSHA: pushed, RemoteRef: "refs/heads/main", RemoteSHA: pushed}},
+			want: false,
+		},
+		{
+			desc: "remote commit that isn't fetched",
+			refs: []prepush.Ref{{LocalRef: "refs/heads/main", LocalSHA: head, RemoteRef: "refs/heads/main", RemoteSHA: unknown}},
+			want: true,
+		},
+		{
+			desc: "deleted branch and a tag",
+			refs: []prepush.Ref{
+				{LocalRef: "(delete)", LocalSHA: zero, RemoteRef: "refs/heads/old", RemoteSHA: pushed},
+				{LocalRef: "refs/tags/v1.0.0", LocalSHA: head, RemoteRef: "refs/tags/v1.0.0", RemoteSHA: zero},
+			},
+			want: false,
+		},
+	} {
+		forgetCaches()
+		push = &prepush.Push{Remote: "origin", Refs: test.refs}
+		if got, err := localIsAhead(); err != nil || got != test.want {
+			t.Errorf("localIsAhead() for a %v = %v,%v, want %v,nil", test.desc, got, err, test.want)
+		}
+	}
+}
+
+func TestPushedTags(t *testing.T) {
+	newRepo(t, map[string]string{"go.mod": "module github.com/x/m\n\ngo 1.22\n"})
+	git(t, "tag", "-a", "v1.1.0", "-m", "v1.1.0")
+	addRemote(t)
+	commit(t, "a.txt", "1\n", "fix: one")
+	head := strings.TrimSpace(git(t, "rev-parse", "HEAD"))
+	const zero = "0000000000000000000000000000000000000000"
+
+	for _, test := range []struct {
+		tag     string
+		wantErr string
+	}{
+		{tag: "v1.0.5", wantErr: "pushed tag v1.0.5 should indicate a higher version than the remote tag v1.1.0"},
+		{tag: "v1.1.0", wantErr: "pushed tag v1.1.0 should indicate a higher version than the remote tag v1.1.0"},
+		{tag: "v1.1.1", wantErr: ""},
+	} {
+		forgetCaches()
+		git(t, "tag", "-f", "-a", test.tag, "-m", test.tag)
+		push = &prepush.Push{Remote: "origin", Refs: []prepush.Ref{
+			{LocalRef: "refs/tags/" + test.tag, LocalSHA: head, RemoteRef: "refs/tags/" + test.tag, RemoteSHA: zero},
+		}}
+		fs := errs.New("gittag")
+		if err := gitTag(fs); err != nil {
+			t.Fatalf("gitTag() pushing %v = %v, want nil error", test.tag, err)
+		}
+		var errors []string
+		for _, f := range fs.List {
+			if f.Severity == errs.Error {
+				errors = append(errors, f.Msg)
+			}
+		}
+		if test.wantErr == "" && len(errors) > 0 || test.wantErr != "" && !slices.Contains(errors, test.wantErr) {
+			t.Errorf("gitTag() pushing %v reported errors %q, want %q", test.tag, errors, test.wantErr)
+		}
+	}
+}
+
+func TestAPIBreaks(t *testing.T) {
+	const major = "doesn't bump the major version, but the exported API has breaking changes"
+	for _, test := range []struct {
+		localTag string
+		wantErr  bool
+	}{
+		{localTag: "v1.1.0", wantErr: true},
+		{localTag: "v2.0.0", wantErr: false},
+	} {
+		newRepo(t, map[string]string{
+			"go.mod": "module github.com/x/m\n\ngo 1.22\n",
+			"m.go":   "package m\n\nfunc F() {}\n\nfunc G() {}\n",
+		})
+		git(t, "tag", "-a", "v1.0.0", "-m", "v1.0.0")
+		addRemote(t)
+		commit(t, "m.go", "package m\n\nfunc F() {}\n", "feat: drop G")
+		git(t, "tag", "-a", test.localTag, "-m", test.localTag)
+
+		fs := errs.New("gittag")
+		if err := gitTag(fs); err != nil {
+			t.Fatalf("gitTag() = %v, want nil error", err)
+		}
+		gotErr, gotBreak := false, false
+		for _, f := range fs.List {
+			gotErr = gotErr || (f.Severity == errs.Error && strings.Contains(f.Msg, major))
+			gotBreak = gotBreak || strings.Contains(f.Msg, "breaking API change since v1.0.0")
+		}
+		if gotErr != test.wantErr || fs.Failed() != test.wantErr {
+			t.Errorf("removing G and tagging %v: major bump error %v, failed %v, want %v; findings %+v",
+				test.localTag, gotErr, fs.Failed(), test.wantErr, fs.List)
+		}
+		if !gotBreak {
+			t.Errorf("removing G and tagging %v: no breaking change reported, findings %+v", test.localTag, fs.List)
+		}
+		if got := git(t, "worktree", "list"); strings.Count(got, "\n") != 1 {
+			t.Errorf("gitTag() left a temporary worktree behind: %q", got)
+		}
+	}
+}
+
+func TestFormatOnlyForChecks(t *testing.T) {
+	newRepo(t, map[string]string{"a.txt": "1\n"})
+	t.Setenv("GOGIT_TEST_MAIN", "1")
+	for _, args := range [][]string{
+		{"--format=json", "install-hooks"},
+		{"--format=sarif", "bump", "minor"},
+		{"format", "a.go", "--format=json"},
+	} {
+		cmd := exec.Command(os.Args[0], args...)
+		b, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(b), "only applies to checks") {
+			t.Errorf("gogit %v = %v, %q, want a usage error", strings.Join(args, " "), err, b)
+		}
+	}
+	if _, err := os.Stat(".git/hooks/pre-commit"); err == nil {
+		t.Errorf("gogit --format=json install-hooks installed the hooks, want it rejected")
+	}
+}
+
+func TestUntaggedWarnedOnce(t *testing.T) {
+	newRepo(t, map[string]string{
+		"go.mod":       "module github.com/x/m\n\ngo 1.22\n",
+		"tools/go.mod": "module github.com/x/m/tools\n\ngo 1.22\n",
+	})
+	git(t, "tag", "-a", "v0.1.0", "-m", "v0.1.0")
+	addRemote(t)
+
+	var warnings []string
+	for name, check := range map[string]func(*errs.Findings) error{"gittag": gitTag, "modpath": modPath} {
+		fs := errs.New(name)
+		if err := check(fs); err != nil || fs.Failed() {
+			t.Fatalf("%v() = %v with findings %+v, want the untagged module skipped", name, err, fs.List)
+		}
+		for _, f := range fs.List {
+			if f.Severity == errs.Warn {
+				warnings = append(warnings, f.String())
+			}
+		}
+	}
+	want := []string{"tools/go.mod: local tag of module tools not found, skipping its tag checks, for a first tagging, run:"}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("warnings = %q, want %q", warnings, want)
+	}
+}
+
+func TestRefreshTOC(t *testing.T) {
+	newRepo(t, map[string]string{"README.md": "# M\n\n<!-- toc -->\n<!-- /toc -->\n\n## Usage\n"})
+	if err := os.Chmod("README.md", 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := refreshTOC("README.md"); err != nil {
+		t.Fatalf("refreshTOC() = %v, want nil error", err)
+	}
+	if got := read(t, "README.md"); !strings.Contains(got, "- [Usage](#usage)") {
+		t.Errorf("after refreshTOC(), README.md = %q, want an entry for Usage", got)
+	}
+	st, err := os.Stat("README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode().Perm() != 0600 {
+		t.Errorf("after refreshTOC(), README.md has mode %v, want 0600 kept", st.Mode())
+	}
+}
+
+func TestRefreshEmbeds(t *testing.T) {
+	newRepo(t, map[string]string{
+		"a.go":      "package a\n\n// F is embedded.\nfunc F() {}\n",
+		"README.md": "# M\n\n<!-- embed: a.go F -->\n```go\nfunc Old() {}\n```\n",
+	})
+	if err := os.Chmod("README.md", 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := refreshEmbeds("README.md"); err != nil {
+		t.Fatalf("refreshEmbeds() = %v, want nil error", err)
+	}
+	if got, want := read(t, "README.md"), "# M\n\n<!-- embed: a.go F -->\n```go\nfunc F() {}\n```\n"; got != want {
+		t.Errorf("after refreshEmbeds(), README.md = %q, want %q", got, want)
+	}
+	st, err := os.Stat("README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode().Perm() != 0600 {
+		t.Errorf("after refreshEmbeds(), README.md has mode %v, want 0600 kept", st.Mode())
+	}
 }
@@ -0,0 +1,112 @@
+// Package gomod inspects go.mod files: the go and toolchain directives against the installed Go,
+// and replace directives that point at the local filesystem.
+package gomod
+
+import (
+	"fmt"
+	"go/version"
+	"os"
+	"strings"
+
+	"github.com/KarelKubat/gogit/errs"
+	"golang.org/x/mod/modfile"
+)
+
+// Issue is a problem in a go.mod file.
+type Issue struct {
+	Severity errs.Severity
+	Line     int // 0 when unknown
+	Msg      string
+	Edit     string // flags for `go mod edit` that resolve the issue, if any
+}
+
+// Parse reads and parses a go.mod file.
+func Parse(fname string) (*modfile.File, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	return modfile.Parse(fname, data, nil)
+}
+
+// Versions checks the go and toolchain directives against the installed Go, e.g. "go1.22.3" from
+// `go env GOVERSION`. The go directive may not be newer than the installed Go, and a toolchain
+// must be valid and not older than the go directive. A toolchain newer than the installed Go is a
+// warning, as the go command then downloads it.
+func Versions(f *modfile.File, installed string) []Issue {
+	var issues []Issue
+	if !version.IsValid(installed) {
+		installed = "" // e.g. a development version, don't compare
+	}
+	if f.Go == nil {
+		edit := ""
+		if installed != "" {
+			edit = "-go=" + strings.TrimPrefix(version.Lang(installed), "go")
+		}
+		return append(issues, Issue{
+			Severity: errs.Warn,
+			Msg:      "go.mod lacks a go directive",
+			Edit:     edit,
+		})
+	}
+	goVersion := "go" + f.Go.Version
+	if installed != "" && version.Compare(goVersion, installed) > 0 {
+		issues = append(issues, Issue{
+			Severity: errs.Error,
+			Line:     f.Go.Syntax.Start.Line,
+			Msg: fmt.Sprintf("go directive %v is newer than the installed %v, install %v or lower the go directive",
+				f.Go.Version, installed, goVersion),
+			Edit: "-go=" + strings.TrimPrefix(installed, "go"),
+		})
+	}
+	if f.Toolchain == nil {
+		return issues
+	}
+	tc := f.Toolchain.Name
+	line := f.Toolchain.Syntax.Start.Line
+	switch {
+	case !version.IsValid(tc):
+		issues = append(issues, Issue{
+			Severity: errs.Error,
+			Line:     line,
+			Msg:      fmt.Sprintf("toolchain %q isn't a valid Go toolchain name, such as go1.22.3", tc),
+			Edit:     "-toolchain=none",
+		})
+	case version.Compare(tc, goVersion) < 0:
+		issues = append(issues, Issue{
+			Severity: errs.Error,
+			Line:     line,
+			Msg:      fmt.Sprintf("toolchain %v is older than the go directive %v", tc, f.Go.Version),
+			Edit:     "-toolchain=none",
+		})
+	case installed != "" && version.Compare(tc, installed) > 0:
+		issues = append(issues, Issue{
+			Severity: errs.Warn,
+			Line:     line,
+			Msg:      fmt.Sprintf("toolchain %v is newer than the installed %v, the go command will download it", tc, installed),
+		})
+	}
+	return issues
+}
+
+// LocalReplaces returns an issue for each replace directive that points at a local directory,
+// which only works on this machine.
+func LocalReplaces(f *modfile.File) []Issue {
+	var issues []Issue
+	for _, r := range f.Replace {
+		if r.New.Version != "" || !modfile.IsDirectoryPath(r.New.Path) {
+			continue
+		}
+		old := r.Old.Path
+		if r.Old.Version != "" {
+			old += "@" + r.Old.Version
+		}
+		issues = append(issues, Issue{
+			Severity: errs.Error,
+			Line:     r.Syntax.Start.Line,
+			Msg:      fmt.Sprintf("%v is replaced by the local directory %v", old, r.New.Path),
+			Edit:     "-dropreplace=" + old,
+		})
+	}
+	return issues
+}
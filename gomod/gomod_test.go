@@ -0,0 +1,120 @@
+package gomod
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/KarelKubat/gogit/errs"
+	"golang.org/x/mod/modfile"
+)
+
+func parse(t *testing.T, content string) *modfile.File {
+	t.Helper()
+	fname := filepath.Join(t.TempDir(), "go.mod")
+	if err := os.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(fname)
+	if err != nil {
+		t.Fatalf("Parse(%q) = _,%v", content, err)
+	}
+	return f
+}
+
+func TestParse(t *testing.T) {
+	// Comments, blank lines and quoted module paths are fine.
+	f := parse(t, "// a comment\n\nmodule \"github.com/x/y\"\n\ngo 1.22\n")
+	if got := f.Module.Mod.Path; got != "github.com/x/y" {
+		t.Errorf("Parse() module path = %q, want github.com/x/y", got)
+	}
+	if _, err := Parse(filepath.Join(t.TempDir(), "go.mod")); err == nil {
+		t.Errorf("Parse(missing) = _,nil, want error")
+	}
+}
+
+func TestVersions(t *testing.T) {
+	for _, test := range []struct {
+		content   string
+		installed string
+		want      []Issue
+	}{
+		{
+			content:   "module x\n\ngo 1.22\n",
+			installed: "go1.22.3",
+			want:      nil,
+		},
+		{
+			content:   "module x\n\ngo 1.22.3\n\ntoolchain go1.23.1\n",
+			installed: "go1.23.1",
+			want:      nil,
+		},
+		{
+			content:   "module x\n",
+			installed: "go1.22.3",
+			want:      []Issue{{Severity: errs.Warn, Msg: "go.mod lacks a go directive", Edit: "-go=1.22"}},
+		},
+		{
+			content:   "module x\n\ngo 1.23\n",
+			installed: "go1.22.3",
+			want: []Issue{{
+				Severity: errs.Error,
+				Line:     3,
+				Msg:      "go directive 1.23 is newer than the installed go1.22.3, install go1.23 or lower the go directive",
+				Edit:     "-go=1.22.3",
+			}},
+		},
+		{
+			content:   "module x\n\ngo 1.23\n",
+			installed: "devel go1.24-abcdef",
+			want:      nil,
+		},
+		{
+			content:   "module x\n\ngo 1.22.3\n\ntoolchain go1.21.0\n",
+			installed: "go1.22.3",
+			want: []Issue{{
+				Severity: errs.Error,
+				Line:     5,
+				Msg:      "toolchain go1.21.0 is older than the go directive 1.22.3",
+				Edit:     "-toolchain=none",
+			}},
+		},
+		{
+			content:   "module x\n\ngo 1.22.3\n\ntoolchain go1.24.0\n",
+			installed: "go1.22.3",
+			want: []Issue{{
+				Severity: errs.Warn,
+				Line:     5,
+				Msg:      "toolchain go1.24.0 is newer than the installed go1.22.3, the go command will download it",
+			}},
+		},
+	} {
+		if got := Versions(parse(t, test.content), test.installed); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Versions(%q, %q) = %+v, want %+v", test.content, test.installed, got, test.want)
+		}
+	}
+}
+
+func TestLocalReplaces(t *testing.T) {
+	f := parse(t, `module x
+
+go 1.22
+
+replace github.com/a/b => ../b
+
+replace (
+	github.com/c/d v1.2.3 => ./vendored/d
+	github.com/e/f => github.com/fork/f v1.0.0
+	github.com/g/h => /abs/h
+)
+`)
+	want := []Issue{
+		{Severity: errs.Error, Line: 5, Msg: "github.com/a/b is replaced by the local directory ../b", Edit: "-dropreplace=github.com/a/b"},
+		{Severity: errs.Error, Line: 8, Msg: "github.com/c/d@v1.2.3 is replaced by the local directory ./vendored/d", Edit: "-dropreplace=github.com/c/d@v1.2.3"},
+		{Severity: errs.Error, Line: 10, Msg: "github.com/g/h is replaced by the local directory /abs/h", Edit: "-dropreplace=github.com/g/h"},
+	}
+	if got := LocalReplaces(f); !reflect.DeepEqual(got, want) {
+		t.Errorf("LocalReplaces() = %+v, want %+v", got, want)
+	}
+}
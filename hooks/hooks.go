@@ -0,0 +1,121 @@
+// Package hooks writes, recognizes and removes the git hooks that run gogit. An existing hook
+// that wasn't written by gogit is kept as <hook>.gogit-orig and chained: it runs first, and when
+// it fails, its exit status is returned without running gogit.
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Marker identifies a hook written by gogit.
+	Marker = "# installed by gogit, remove using: gogit uninstall-hooks"
+
+	// Suffix of a foreign hook that is chained by the gogit hook.
+	OrigSuffix = ".gogit-orig"
+
+	// Template of a hook script. The parameters are the marker, the hook name and the command
+	// that runs gogit. Stdin is buffered so that both the chained hook and gogit can read it.
+	script = `#!/bin/sh
+%v
+input=$(cat)
+feed() {
+	[ -z "$input" ] || printf '%%s\n' "$input"
+}
+orig="$(dirname "$0")/%v` + OrigSuffix + `"
+if [ -x "$orig" ]; then
+	feed | "$orig" "$@" || exit $?
+fi
+feed | %v "$@"
+`
+)
+
+// Names of the hooks that gogit installs.
+var Names = []string{"pre-commit", "pre-push"}
+
+// Script returns the hook script for a hook name.
+func Script(hook string) string {
+	return fmt.Sprintf(script, Marker, hook, "gogit "+hook)
+}
+
+// IsGogit returns true when the hook file was written by gogit. Hooks of older gogit versions,
+// which were just `exec gogit <hook>`, are recognized too.
+func IsGogit(path string) (bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	content := string(b)
+	if strings.Contains(content, Marker) {
+		return true, nil
+	}
+	return strings.TrimSpace(content) == "exec gogit "+filepath.Base(path), nil
+}
+
+// Install writes the hook into the hooks directory. A foreign hook is moved aside to be chained.
+// The returned string describes what was done.
+func Install(dir, hook string) (string, error) {
+	path := filepath.Join(dir, hook)
+	orig := path + OrigSuffix
+	what := fmt.Sprintf("hook %v installed", path)
+
+	gogit, err := IsGogit(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// Fresh install.
+	case err != nil:
+		return "", err
+	case !gogit:
+		if _, err := os.Stat(orig); err == nil {
+			return "", fmt.Errorf("can't chain hook %v: %v already exists, won't overwrite", path, orig)
+		}
+		if err := os.Rename(path, orig); err != nil {
+			return "", fmt.Errorf("can't move existing hook %v aside: %v", path, err)
+		}
+		what = fmt.Sprintf("hook %v installed, existing hook is moved to %v and runs first", path, orig)
+	default:
+		what = fmt.Sprintf("hook %v refreshed", path)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("can't create hooks directory %v: %v", dir, err)
+	}
+	if err := os.WriteFile(path, []byte(Script(hook)), 0755); err != nil {
+		return "", fmt.Errorf("failed to write hook %v: %v", path, err)
+	}
+	// WriteFile doesn't change the mode of an existing file.
+	if err := os.Chmod(path, 0755); err != nil {
+		return "", fmt.Errorf("failed to make hook %v executable: %v", path, err)
+	}
+	return what, nil
+}
+
+// Uninstall removes a gogit hook and restores a chained foreign hook, if any. A foreign hook is
+// left alone. The returned string describes what was done.
+func Uninstall(dir, hook string) (string, error) {
+	path := filepath.Join(dir, hook)
+	orig := path + OrigSuffix
+
+	gogit, err := IsGogit(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return fmt.Sprintf("hook %v not installed", path), nil
+	case err != nil:
+		return "", err
+	case !gogit:
+		return fmt.Sprintf("hook %v was not installed by gogit, left alone", path), nil
+	}
+	if err := os.Remove(path); err != nil {
+		return "", fmt.Errorf("failed to remove hook %v: %v", path, err)
+	}
+	if _, err := os.Stat(orig); err != nil {
+		return fmt.Sprintf("hook %v removed", path), nil
+	}
+	if err := os.Rename(orig, path); err != nil {
+		return "", fmt.Errorf("failed to restore hook %v from %v: %v", path, orig, err)
+	}
+	return fmt.Sprintf("hook %v removed, original hook restored", path), nil
+}
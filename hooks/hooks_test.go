@@ -0,0 +1,147 @@
+package hooks
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsGogit(t *testing.T) {
+	dir := t.TempDir()
+	for _, test := range []struct {
+		content   string
+		wantGogit bool
+	}{
+		{
+			content:   Script("pre-commit"),
+			wantGogit: true,
+		},
+		{
+			content:   "exec gogit pre-commit\n",
+			wantGogit: true,
+		},
+		{
+			content:   "#!/bin/sh\nmake lint\n",
+			wantGogit: false,
+		},
+	} {
+		path := filepath.Join(dir, "pre-commit")
+		if err := os.WriteFile(path, []byte(test.content), 0755); err != nil {
+			t.Fatal(err)
+		}
+		gotGogit, err := IsGogit(path)
+		if err != nil {
+			t.Fatalf("IsGogit(%q) = _,%q, want nil error", test.content, err.Error())
+		}
+		if gotGogit != test.wantGogit {
+			t.Errorf("IsGogit(%q) = %v, want %v", test.content, gotGogit, test.wantGogit)
+		}
+	}
+}
+
+func TestInstallUninstall(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pre-commit")
+	foreign := "#!/bin/sh\nexit 0\n"
+
+	// Fresh install, refresh, uninstall.
+	if _, err := Install(dir, "pre-commit"); err != nil {
+		t.Fatalf("Install() = _,%q, want nil error", err.Error())
+	}
+	if what, err := Install(dir, "pre-commit"); err != nil || !strings.Contains(what, "refreshed") {
+		t.Errorf("Install() again = %q,%v, want refresh and nil error", what, err)
+	}
+	if _, err := Uninstall(dir, "pre-commit"); err != nil {
+		t.Fatalf("Uninstall() = _,%q, want nil error", err.Error())
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("after Uninstall(): %v still exists", path)
+	}
+
+	// Chaining a foreign hook, uninstalling restores it.
+	if err := os.WriteFile(path, []byte(foreign), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if what, err := Install(dir, "pre-commit"); err != nil || !strings.Contains(what, OrigSuffix) {
+		t.Errorf("Install() over foreign hook = %q,%v, want chaining and nil error", what, err)
+	}
+	if b, err := os.ReadFile(path + OrigSuffix); err != nil || string(b) != foreign {
+		t.Errorf("chained hook = %q,%v, want %q", string(b), err, foreign)
+	}
+	if _, err := Uninstall(dir, "pre-commit"); err != nil {
+		t.Fatalf("Uninstall() = _,%q, want nil error", err.Error())
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != foreign {
+		t.Errorf("restored hook = %q,%v, want %q", string(b), err, foreign)
+	}
+
+	// A foreign hook is left alone.
+	if what, err := Uninstall(dir, "pre-commit"); err != nil || !strings.Contains(what, "left alone") {
+		t.Errorf("Uninstall() of foreign hook = %q,%v, want it left alone", what, err)
+	}
+}
+
+func TestChaining(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh available")
+	}
+	dir := t.TempDir()
+	bin := t.TempDir()
+	log := filepath.Join(t.TempDir(), "log")
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// A fake gogit logs its args and stdin.
+	fake := "#!/bin/sh\necho gogit \"$@\" >> " + log + "\ncat >> " + log + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "gogit"), []byte(fake), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		foreignExit string
+		wantExit    int
+		wantLog     string
+	}{
+		{
+			foreignExit: "0",
+			wantExit:    0,
+			wantLog:     "foreign origin\nref-line\ngogit pre-push origin\nref-line\n",
+		},
+		{
+			foreignExit: "3",
+			wantExit:    3,
+			wantLog:     "foreign origin\nref-line\n",
+		},
+	} {
+		os.Remove(log)
+		os.Remove(filepath.Join(dir, "pre-push"+OrigSuffix))
+		path := filepath.Join(dir, "pre-push")
+		foreign := "#!/bin/sh\necho foreign \"$1\" >> " + log + "\ncat >> " + log + "\nexit " + test.foreignExit + "\n"
+		if err := os.WriteFile(path, []byte(foreign), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Install(dir, "pre-push"); err != nil {
+			t.Fatalf("Install() = _,%q, want nil error", err.Error())
+		}
+
+		cmd := exec.Command(path, "origin")
+		cmd.Stdin = strings.NewReader("ref-line\n")
+		err := cmd.Run()
+		gotExit := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			gotExit = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatalf("running hook: %v", err)
+		}
+		if gotExit != test.wantExit {
+			t.Errorf("hook with foreign exit %v: exit = %v, want %v", test.foreignExit, gotExit, test.wantExit)
+		}
+		b, _ := os.ReadFile(log)
+		if string(b) != test.wantLog {
+			t.Errorf("hook with foreign exit %v: log = %q, want %q", test.foreignExit, string(b), test.wantLog)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+// Package markdown parses the parts of Markdown documents that gogit maintains: fenced code blocks,
+// headings and the table of contents, using GitHub's rules for heading anchors.
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// Markers of the table of contents, each on a line of its own.
+	TOCStart = "<!-- toc -->"
+	TOCEnd   = "<!-- /toc -->"
+)
+
+// Fence is a fenced code block, opened and closed by ``` or ~~~.
+type Fence struct {
+	Info   string   // info string after the opening fence, e.g. "go"
+	Indent int      // indentation of the opening fence, 0-3 spaces
+	Start  int      // line number of the opening fence, 1-based
+	End    int      // line number of the closing fence, 0 when unclosed
+	Lines  []string // content between the fences
+}
+
+// Lang returns the first word of the info string, e.g. "go" for "go title=x".
+func (f Fence) Lang() string {
+	if fields := strings.Fields(f.Info); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// Fences returns the fenced code blocks of a document, following CommonMark: a fence is at least
+// three backticks or tildes, indented by at most three spaces, and closed by a fence of the same
+// character that is at least as long. An unclosed block runs to the end of the document.
+func Fences(content string) []Fence {
+	var fences []Fence
+	var open *Fence
+	var marker string
+	for i, l := range strings.Split(content, "\n") {
+		if open == nil {
+			if indent, m, info, ok := opening(l); ok {
+				open = &Fence{Info: info, Indent: indent, Start: i + 1}
+				marker = m
+			}
+			continue
+		}
+		if closing(l, marker) {
+			open.End = i + 1
+			fences = append(fences, *open)
+			open = nil
+			continue
+		}
+		open.Lines = append(open.Lines, l)
+	}
+	if open != nil {
+		fences = append(fences, *open)
+	}
+	return fences
+}
+
+// InFence returns, per line (0-based), whether it is part of a fenced code block, fences included.
+func InFence(content string) []bool {
+	in := make([]bool, strings.Count(content, "\n")+1)
+	for _, f := range Fences(content) {
+		end := f.End
+		if end == 0 {
+			end = len(in)
+		}
+		for i := f.Start - 1; i < end; i++ {
+			in[i] = true
+		}
+	}
+	return in
+}
+
+// opening returns the indentation, the fence marker and the info string of an opening fence.
+func opening(l string) (indent int, marker, info string, ok bool) {
+	indent = len(l) - len(strings.TrimLeft(l, " "))
+	if indent > 3 {
+		return 0, "", "", false
+	}
+	rest := l[indent:]
+	if rest == "" || (rest[0] != '`' && rest[0] != '~') {
+		return 0, "", "", false
+	}
+	n := len(rest) - len(strings.TrimLeft(rest, rest[:1]))
+	if n < 3 {
+		return 0, "", "", false
+	}
+	info = strings.TrimSpace(rest[n:])
+	if rest[0] == '`' && strings.Contains(info, "`") {
+		return 0, "", "", false // inline code, not a fence
+	}
+	return indent, rest[:n], info, true
+}
+
+// closing returns true when a line closes a fence that was opened with marker.
+func closing(l, marker string) bool {
+	trimmed := strings.TrimLeft(l, " ")
+	if len(l)-len(trimmed) > 3 || !strings.HasPrefix(trimmed, marker) {
+		return false
+	}
+	return strings.TrimSpace(strings.TrimLeft(trimmed, marker[:1])) == ""
+}
+
+// Heading is an ATX heading, e.g. "## Installation".
+type Heading struct {
+	Level  int    // 1-6
+	Text   string // without the leading #s, e.g. "`git commit` phase"
+	Line   int    // 1-based
+	Anchor string // GitHub's anchor, unique within the document, e.g. "git-commit-phase"
+}
+
+var (
+	headingRe = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	linkRe    = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	tagRe     = regexp.MustCompile(`<[^>]*>`)
+	codeRe    = regexp.MustCompile("`([^`]+)`")
+	anchorRe  = regexp.MustCompile(`<[a-zA-Z][a-zA-Z0-9]*\s[^>]*\b(?:name|id)="([^"]+)"`)
+)
+
+// Headings returns the headings of a document outside of fenced code blocks. Duplicate anchors
+// get a suffix -1, -2 etc., as on GitHub.
+func Headings(content string) []Heading {
+	var hs []Heading
+	seen := map[string]int{}
+	in := InFence(content)
+	for i, l := range strings.Split(content, "\n") {
+		if in[i] {
+			continue
+		}
+		m := headingRe.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		h := Heading{Level: len(m[1]), Text: m[2], Line: i + 1}
+		h.Anchor = unique(Slug(h.Text), seen)
+		hs = append(hs, h)
+	}
+	return hs
+}
+
+// Anchors returns the anchors of a document, to validate #anchor links: those of the headings and
+// of HTML elements such as <a name="x">.
+func Anchors(content string) map[string]struct{} {
+	anchors := map[string]struct{}{}
+	for _, h := range Headings(content) {
+		anchors[h.Anchor] = struct{}{}
+	}
+	in := InFence(content)
+	for i, l := range strings.Split(content, "\n") {
+		if in[i] {
+			continue
+		}
+		for _, m := range anchorRe.FindAllStringSubmatch(l, -1) {
+			anchors[m[1]] = struct{}{}
+		}
+	}
+	return anchors
+}
+
+// Slug returns GitHub's anchor for a heading text: the text as rendered (without markup such as
+// backticks, links and HTML tags) in lower case, where spaces become dashes and punctuation other
+// than dashes and underscores is dropped.
+func Slug(text string) string {
+	text = linkRe.ReplaceAllString(text, "$1")
+	text = tagRe.ReplaceAllString(text, "")
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// unique returns the slug, or when it was seen before, the slug with the first free suffix.
+func unique(slug string, seen map[string]int) string {
+	anchor := slug
+	for {
+		if _, ok := seen[anchor]; !ok {
+			break
+		}
+		seen[slug]++
+		anchor = fmt.Sprintf("%v-%v", slug, seen[slug])
+	}
+	seen[anchor] = 0
+	return anchor
+}
+
+// TOC returns the lines of a table of contents of the headings with levels minLevel up to and
+// including maxLevel, indented by two spaces per level. Code spans in the headings become
+// <code> elements, as mdtoc renders them.
+func TOC(hs []Heading, minLevel, maxLevel int) []string {
+	var lines []string
+	for _, h := range hs {
+		if h.Level < minLevel || h.Level > maxLevel {
+			continue
+		}
+		text := codeRe.ReplaceAllString(h.Text, "<code>$1</code>")
+		lines = append(lines, fmt.Sprintf("%v- [%v](#%v)", strings.Repeat("  ", h.Level-minLevel), text, h.Anchor))
+	}
+	return lines
+}
+
+// UpdateTOC returns the document with the table of contents between the markers TOCStart and
+// TOCEnd regenerated. The markers must be present once each, outside of code blocks.
+func UpdateTOC(content string, minLevel, maxLevel int) (string, error) {
+	lines := strings.Split(content, "\n")
+	in := InFence(content)
+	start, end := -1, -1
+	for i, l := range lines {
+		if in[i] {
+			continue
+		}
+		switch strings.TrimSpace(l) {
+		case TOCStart:
+			if start >= 0 {
+				return "", fmt.Errorf("%v found twice, at lines %v and %v", TOCStart, start+1, i+1)
+			}
+			start = i
+		case TOCEnd:
+			if end >= 0 {
+				return "", fmt.Errorf("%v found twice, at lines %v and %v", TOCEnd, end+1, i+1)
+			}
+			end = i
+		}
+	}
+	switch {
+	case start < 0 || end < 0:
+		return "", fmt.Errorf("%v and %v must both be present", TOCStart, TOCEnd)
+	case end < start:
+		return "", fmt.Errorf("%v at line %v precedes %v at line %v", TOCEnd, end+1, TOCStart, start+1)
+	}
+	toc := TOC(Headings(content), minLevel, maxLevel)
+	updated := append(append(append([]string{}, lines[:start+1]...), toc...), lines[end:]...)
+	return strings.Join(updated, "\n"), nil
+}
+
+// Link is a link in a document: an inline link or image [text](target), a reference definition
+// [label]: target, or an autolink <https://...>.
+type Link struct {
+	Target string
+	Line   int // 1-based
+	Column int // 1-based
+}
+
+var (
+	inlineLinkRe = regexp.MustCompile(`!?\[[^\]]*\]\(\s*(<[^>]*>|[^)\s]+)(?:\s+(?:"[^"]*"|'[^']*'))?\s*\)`)
+	refLinkRe    = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*(<[^>]*>|\S+)`)
+	autoLinkRe   = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9+.-]*:[^\s<>]*)>`)
+	codeSpanRe   = regexp.MustCompile("(`+)[^`]*?(`+)")
+	schemeRe     = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// Links returns the links of a document, outside of code blocks and code spans.
+func Links(content string) []Link {
+	var links []Link
+	in := InFence(content)
+	for i, l := range strings.Split(content, "\n") {
+		if in[i] {
+			continue
+		}
+		// Blank out code spans, keeping the columns.
+		l = codeSpanRe.ReplaceAllStringFunc(l, func(span string) string {
+			return strings.Repeat(" ", len(span))
+		})
+		for _, re := range []*regexp.Regexp{inlineLinkRe, refLinkRe, autoLinkRe} {
+			for _, m := range re.FindAllStringSubmatchIndex(l, -1) {
+				target := strings.TrimSuffix(strings.TrimPrefix(l[m[2]:m[3]], "<"), ">")
+				links = append(links, Link{Target: target, Line: i + 1, Column: m[2] + 1})
+			}
+		}
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].Line != links[j].Line {
+			return links[i].Line < links[j].Line
+		}
+		return links[i].Column < links[j].Column
+	})
+	return links
+}
+
+// External returns true when the link has a scheme, e.g. https: or mailto:.
+func (l Link) External() bool {
+	return schemeRe.MatchString(l.Target)
+}
+
+// Split returns the path and the anchor of a link, e.g. "docs/x.md" and "usage" for
+// "docs/x.md#usage"; the path is empty for a link within the document. A query is dropped.
+func (l Link) Split() (path, anchor string) {
+	path, anchor, _ = strings.Cut(l.Target, "#")
+	path, _, _ = strings.Cut(path, "?")
+	return path, anchor
+}
+
+// Untab expands the tabs of a line in a code block to spaces, with tab stops every four columns,
+// as GitHub renders tabs eight wide.
+func Untab(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var b strings.Builder
+	col := 0
+	for _, r := range line {
+		if r != '\t' {
+			b.WriteRune(r)
+			col++
+			continue
+		}
+		n := 4 - col%4
+		b.WriteString(strings.Repeat(" ", n))
+		col += n
+	}
+	return b.String()
+}
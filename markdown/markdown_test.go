@@ -0,0 +1,236 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFences(t *testing.T) {
+	doc := strings.Join([]string{
+		"# Title",           // 1
+		"```go",             // 2
+		"x := 1",            // 3
+		"```",               // 4
+		"   ~~~~ makefile",  // 5
+		"all:",              // 6
+		"~~~",               // 7, too short to close
+		"\tgo build",        // 8
+		"~~~~~",             // 9
+		"``` not `a` fence", // 10
+		"    ```",           // 11, indented code, not a fence
+		"````",              // 12
+		"```",               // 13, too short to close
+		"unclosed",          // 14
+	}, "\n")
+	want := []Fence{
+		{Info: "go", Start: 2, End: 4, Lines: []string{"x := 1"}},
+		{Info: "makefile", Indent: 3, Start: 5, End: 9, Lines: []string{"all:", "~~~", "\tgo build"}},
+		{Info: "", Start: 12, End: 0, Lines: []string{"```", "unclosed"}},
+	}
+	if got := Fences(doc); !reflect.DeepEqual(got, want) {
+		t.Errorf("Fences() = %+v, want %+v", got, want)
+	}
+	if got := want[1].Lang(); got != "makefile" {
+		t.Errorf("Lang() = %q, want makefile", got)
+	}
+	in := InFence(doc)
+	for i, w := range []bool{false, true, true, true, true, true, true, true, true, false, false, true, true, true} {
+		if in[i] != w {
+			t.Errorf("InFence() line %v = %v, want %v", i+1, in[i], w)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		want string
+	}{
+		{text: "What it does", want: "what-it-does"},
+		{text: "`git commit` phase", want: "git-commit-phase"},
+		{text: "<code>git push</code> phase", want: "git-push-phase"},
+		{text: "Suggestion to automatically refresh the Table of Contents", want: "suggestion-to-automatically-refresh-the-table-of-contents"},
+		{text: "What's new? (v2.0)", want: "whats-new-v20"},
+		{text: "A [link](https://example.com) here", want: "a-link-here"},
+		{text: "snake_case and kebab-case", want: "snake_case-and-kebab-case"},
+		{text: "Über  café", want: "über--café"},
+	} {
+		if got := Slug(test.text); got != test.want {
+			t.Errorf("Slug(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestHeadings(t *testing.T) {
+	doc := strings.Join([]string{
+		"# Title",
+		"## Examples",
+		"```sh",
+		"# not a heading",
+		"```",
+		"### Examples",
+		"#### Examples ###",
+		"## Examples-1",
+		"#NoSpace",
+		"####### Seven",
+		"   ## Indented",
+	}, "\n")
+	want := []Heading{
+		{Level: 1, Text: "Title", Line: 1, Anchor: "title"},
+		{Level: 2, Text: "Examples", Line: 2, Anchor: "examples"},
+		{Level: 3, Text: "Examples", Line: 6, Anchor: "examples-1"},
+		{Level: 4, Text: "Examples", Line: 7, Anchor: "examples-2"},
+		{Level: 2, Text: "Examples-1", Line: 8, Anchor: "examples-1-1"},
+		{Level: 2, Text: "Indented", Line: 11, Anchor: "indented"},
+	}
+	if got := Headings(doc); !reflect.DeepEqual(got, want) {
+		t.Errorf("Headings() = %+v, want %+v", got, want)
+	}
+	anchors := Anchors(doc)
+	for _, a := range []string{"title", "examples-2", "examples-1-1"} {
+		if _, ok := anchors[a]; !ok {
+			t.Errorf("Anchors() lacks %q", a)
+		}
+	}
+}
+
+func TestUpdateTOC(t *testing.T) {
+	for _, test := range []struct {
+		doc      string
+		min, max int
+		want     string
+		wantErr  string
+	}{
+		{
+			doc:  "# T\n<!-- toc -->\n- stale\n<!-- /toc -->\n## A\n### `b` c\n#### D\n## A\n",
+			min:  2,
+			max:  3,
+			want: "# T\n<!-- toc -->\n- [A](#a)\n  - [<code>b</code> c](#b-c)\n- [A](#a-1)\n<!-- /toc -->\n## A\n### `b` c\n#### D\n## A\n",
+		},
+		{
+			doc:  "<!-- toc -->\n<!-- /toc -->\n# T\n## A\n",
+			min:  1,
+			max:  6,
+			want: "<!-- toc -->\n- [T](#t)\n  - [A](#a)\n<!-- /toc -->\n# T\n## A\n",
+		},
+		{
+			doc:     "# T\n## A\n",
+			min:     2,
+			max:     6,
+			wantErr: "must both be present",
+		},
+		{
+			doc:     "<!-- /toc -->\n<!-- toc -->\n",
+			min:     2,
+			max:     6,
+			wantErr: "precedes",
+		},
+		{
+			doc:     "<!-- toc -->\n<!-- /toc -->\n<!-- toc -->\n",
+			min:     2,
+			max:     6,
+			wantErr: "found twice",
+		},
+		{
+			doc:     "```\n<!-- toc -->\n<!-- /toc -->\n```\n",
+			min:     2,
+			max:     6,
+			wantErr: "must both be present",
+		},
+	} {
+		got, err := UpdateTOC(test.doc, test.min, test.max)
+		switch {
+		case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+			t.Errorf("UpdateTOC(%q) = _,%v, want error with %q", test.doc, err, test.wantErr)
+		case test.wantErr == "" && err != nil:
+			t.Errorf("UpdateTOC(%q) = _,%v, want nil error", test.doc, err)
+		case got != test.want:
+			t.Errorf("UpdateTOC(%q) = %q, want %q", test.doc, got, test.want)
+		}
+	}
+}
+
+func TestAnchorsHTML(t *testing.T) {
+	anchors := Anchors("# T\n<a name=\"custom\"></a>\n<div id=\"other\">\n```\n<a name=\"fenced\">\n```\n")
+	for _, test := range []struct {
+		anchor string
+		want   bool
+	}{
+		{anchor: "t", want: true},
+		{anchor: "custom", want: true},
+		{anchor: "other", want: true},
+		{anchor: "fenced", want: false},
+	} {
+		if _, got := anchors[test.anchor]; got != test.want {
+			t.Errorf("Anchors() has %q = %v, want %v", test.anchor, got, test.want)
+		}
+	}
+}
+
+func TestLinks(t *testing.T) {
+	doc := strings.Join([]string{
+		"See [the docs](docs/x.md#usage) and ![logo](img/logo.png \"Logo\").",
+		"Jump to [below](#below), or <https://example.com/a>.",
+		"Not in `[code](span.md)`, but [spaced](<a b.md>).",
+		"```",
+		"[fenced](nope.md)",
+		"```",
+		"[ref]: https://example.com/ref",
+		"[q](x.md?raw=1#top) [mail](mailto:a@b.c)",
+	}, "\n")
+	want := []Link{
+		{Target: "docs/x.md#usage", Line: 1, Column: 16},
+		{Target: "img/logo.png", Line: 1, Column: 45},
+		{Target: "#below", Line: 2, Column: 17},
+		{Target: "https://example.com/a", Line: 2, Column: 30},
+		{Target: "a b.md", Line: 3, Column: 40},
+		{Target: "https://example.com/ref", Line: 7, Column: 8},
+		{Target: "x.md?raw=1#top", Line: 8, Column: 5},
+		{Target: "mailto:a@b.c", Line: 8, Column: 28},
+	}
+	got := Links(doc)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Links() = %+v, want %+v", got, want)
+	}
+	for i, test := range []struct {
+		external bool
+		path     string
+		anchor   string
+	}{
+		{path: "docs/x.md", anchor: "usage"},
+		{path: "img/logo.png"},
+		{anchor: "below"},
+		{external: true, path: "https://example.com/a"},
+		{path: "a b.md"},
+		{external: true, path: "https://example.com/ref"},
+		{path: "x.md", anchor: "top"},
+		{external: true, path: "mailto:a@b.c"},
+	} {
+		path, anchor := got[i].Split()
+		if got[i].External() != test.external || path != test.path || anchor != test.anchor {
+			t.Errorf("%+v: External(), Split() = %v, %q, %q, want %v, %q, %q",
+				got[i], got[i].External(), path, anchor, test.external, test.path, test.anchor)
+		}
+	}
+}
+
+func TestUntab(t *testing.T) {
+	for _, test := range []struct {
+		line string
+		want string
+	}{
+		{line: "", want: ""},
+		{line: "x", want: "x"},
+		{line: "x\ty", want: "x   y"},
+		{line: "\tx", want: "    x"},
+		{line: "\t\tx\t", want: "        x   "},
+		{line: "  \tx", want: "    x"},
+		{line: "abcd\te", want: "abcd    e"},
+		{line: "é\tx", want: "é   x"},
+	} {
+		if got := Untab(test.line); got != test.want {
+			t.Errorf("Untab(%q) = %q, want %q", test.line, got, test.want)
+		}
+	}
+}
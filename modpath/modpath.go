@@ -0,0 +1,116 @@
+// Package modpath relates module paths to major versions: from v2 on, a Go module path must end
+// in the major version, e.g. github.com/x/y/v2 for tags v2.x.y.
+package modpath
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ex. github.com/x/y/v2
+var majorSuffixRe = regexp.MustCompile(`^(.+)/v([2-9]|[1-9]\d+)$`)
+
+// Split returns the module path without the major version suffix, and the major version that the
+// suffix indicates: 1 when there is none.
+func Split(path string) (prefix string, major int) {
+	m := majorSuffixRe.FindStringSubmatch(path)
+	if m == nil {
+		return path, 1
+	}
+	major, _ = strconv.Atoi(m[2])
+	return m[1], major
+}
+
+// ForMajor returns the module path that tags of the given major version require. Majors 0 and 1
+// have no suffix.
+func ForMajor(path string, major int) string {
+	prefix, _ := Split(path)
+	if major < 2 {
+		return prefix
+	}
+	return fmt.Sprintf("%v/v%d", prefix, major)
+}
+
+// Import is an import of a package of the module, or of the module itself.
+type Import struct {
+	File string
+	Line int
+	Path string
+}
+
+// Rewrite returns the import path when the module path changes from oldMod to newMod.
+func (i Import) Rewrite(oldMod, newMod string) string {
+	return newMod + strings.TrimPrefix(i.Path, oldMod)
+}
+
+// Imports scans the .go files under root for imports of the module or its packages, in the
+// order of the files. Directories that the go tool ignores (vendor, testdata, names starting with
+// . or _) and nested modules are skipped.
+func Imports(root, modPath string) ([]Import, error) {
+	var imports []Import
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(root, func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == root {
+				return nil
+			}
+			name := d.Name()
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, spec := range f.Imports {
+			p, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			if ownImport(p, modPath) {
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					rel = path
+				}
+				imports = append(imports, Import{
+					File: rel,
+					Line: fset.Position(spec.Pos()).Line,
+					Path: p,
+				})
+			}
+		}
+		return nil
+	})
+	return imports, err
+}
+
+// ownImport is true when the import path is the module path or one of its packages, but not
+// another major version of the module (e.g. github.com/x/y/v2 for module github.com/x/y).
+func ownImport(path, modPath string) bool {
+	if path == modPath {
+		return true
+	}
+	if !strings.HasPrefix(path, modPath+"/") {
+		return false
+	}
+	first, _, _ := strings.Cut(strings.TrimPrefix(path, modPath+"/"), "/")
+	return !majorSuffixRe.MatchString(modPath + "/" + first)
+}
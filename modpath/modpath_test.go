@@ -0,0 +1,111 @@
+package modpath
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitForMajor(t *testing.T) {
+	for _, test := range []struct {
+		path       string
+		major      int
+		wantPrefix string
+		wantMajor  int
+		wantPath   string
+	}{
+		{
+			path:       "github.com/x/y",
+			major:      1,
+			wantPrefix: "github.com/x/y",
+			wantMajor:  1,
+			wantPath:   "github.com/x/y",
+		},
+		{
+			path:       "github.com/x/y",
+			major:      2,
+			wantPrefix: "github.com/x/y",
+			wantMajor:  1,
+			wantPath:   "github.com/x/y/v2",
+		},
+		{
+			path:       "github.com/x/y/v2",
+			major:      3,
+			wantPrefix: "github.com/x/y",
+			wantMajor:  2,
+			wantPath:   "github.com/x/y/v3",
+		},
+		{
+			path:       "github.com/x/y/v12",
+			major:      0,
+			wantPrefix: "github.com/x/y",
+			wantMajor:  12,
+			wantPath:   "github.com/x/y",
+		},
+		{
+			// not a major version suffix
+			path:       "github.com/x/y/v1",
+			major:      1,
+			wantPrefix: "github.com/x/y/v1",
+			wantMajor:  1,
+			wantPath:   "github.com/x/y/v1",
+		},
+	} {
+		prefix, major := Split(test.path)
+		if prefix != test.wantPrefix || major != test.wantMajor {
+			t.Errorf("Split(%q) = %q,%v, want %q,%v", test.path, prefix, major, test.wantPrefix, test.wantMajor)
+		}
+		if got := ForMajor(test.path, test.major); got != test.wantPath {
+			t.Errorf("ForMajor(%q,%v) = %q, want %q", test.path, test.major, got, test.wantPath)
+		}
+	}
+}
+
+func TestImports(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"a.go": `package a
+
+import (
+	"fmt"
+
+	"github.com/x/y/sub"
+)
+`,
+		"sub/b.go":         "package sub\n\nimport \"github.com/x/y\"\n",
+		"sub/c.go":         "package sub\n\nimport \"github.com/x/yz\"\n",
+		"vendor/d.go":      "package d\n\nimport \"github.com/x/y\"\n",
+		"nested/go.mod":    "module github.com/x/y/nested\n",
+		"nested/e.go":      "package nested\n\nimport \"github.com/x/y\"\n",
+		"sub/notes.txt":    "import \"github.com/x/y\"\n",
+		"testdata/f.go":    "package f\n\nimport \"github.com/x/y\"\n",
+		".hidden/g.go":     "package g\n\nimport \"github.com/x/y\"\n",
+		"sub/deep/h_x.go":  "package deep\n\nimport y \"github.com/x/y/sub\"\n",
+		"sub/deep/i_x.go":  "package deep\n",
+		"sub/deep/j_xx.go": "package deep\n\nimport _ \"github.com/x/y/v2/sub\"\n", // another major
+	} {
+		fname := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fname, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := Imports(root, "github.com/x/y")
+	if err != nil {
+		t.Fatalf("Imports() = _,%v", err)
+	}
+	want := []Import{
+		{File: "a.go", Line: 6, Path: "github.com/x/y/sub"},
+		{File: "sub/b.go", Line: 3, Path: "github.com/x/y"},
+		{File: "sub/deep/h_x.go", Line: 3, Path: "github.com/x/y/sub"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Imports() = %+v, want %+v", got, want)
+	}
+	if got := want[0].Rewrite("github.com/x/y", "github.com/x/y/v2"); got != "github.com/x/y/v2/sub" {
+		t.Errorf("Rewrite() = %q, want github.com/x/y/v2/sub", got)
+	}
+}
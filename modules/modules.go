@@ -0,0 +1,190 @@
+// Package modules discovers the Go modules of a repository: the go.mod files in its directories
+// and the modules that a go.work file uses. Nested modules are tagged with their directory as
+// prefix, e.g. tools/v0.3.1 for the module in tools/, as the go command expects.
+package modules
+
+import (
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KarelKubat/gogit/modpath"
+	"golang.org/x/mod/modfile"
+)
+
+const (
+	ModFile  = "go.mod"
+	SumFile  = "go.sum"
+	WorkFile = "go.work"
+)
+
+// Module is a Go module in the repository.
+type Module struct {
+	Dir  string // relative to the top level of the repository, "." for the top level itself
+	Path string // module path from go.mod
+}
+
+// TagPrefix returns the prefix of the tags of the module: "" for the top level, otherwise its
+// directory and a slash. A major version subdirectory (e.g. tools/v2 for module .../tools/v2)
+// isn't part of the prefix.
+func (m Module) TagPrefix() string {
+	dir := m.Dir
+	if _, major := modpath.Split(m.Path); major > 1 && path.Base(dir) == fmt.Sprintf("v%d", major) {
+		dir = path.Dir(dir)
+	}
+	if dir == "." {
+		return ""
+	}
+	return dir + "/"
+}
+
+// TagName returns the git tag for a version of the module, ex. tools/v0.3.1.
+func (m Module) TagName(version string) string {
+	return m.TagPrefix() + version
+}
+
+// Version returns the version part of a git tag of the module, and whether the tag belongs to
+// the module at all.
+func (m Module) Version(tagName string) (string, bool) {
+	prefix := m.TagPrefix()
+	if !strings.HasPrefix(tagName, prefix) {
+		return "", false
+	}
+	version := strings.TrimPrefix(tagName, prefix)
+	if strings.Contains(version, "/") {
+		return "", false // a tag of a nested module
+	}
+	return version, true
+}
+
+// File returns the path of a file in the module, relative to the top level.
+func (m Module) File(name string) string {
+	return path.Join(m.Dir, name)
+}
+
+// Of returns the module that a file or directory belongs to, given relative to the top level:
+// the innermost module whose directory contains it.
+func Of(mods []Module, name string) (Module, bool) {
+	var found Module
+	ok := false
+	for _, m := range mods {
+		if m.Dir != "." && name != m.Dir && !strings.HasPrefix(name, m.Dir+"/") {
+			continue
+		}
+		if !ok || len(m.Dir) > len(found.Dir) || found.Dir == "." {
+			found, ok = m, true
+		}
+	}
+	return found, ok
+}
+
+// Rel returns the path of a file relative to the module directory, given relative to the top
+// level.
+func (m Module) Rel(name string) string {
+	if m.Dir == "." {
+		return name
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(name, m.Dir), "/")
+}
+
+// Ignored is true for the names of directories that the go tool ignores: vendor, testdata, and
+// names starting with . or _.
+func Ignored(dir string) bool {
+	return dir == "vendor" || dir == "testdata" || strings.HasPrefix(dir, ".") || strings.HasPrefix(dir, "_")
+}
+
+// Discover returns the modules under root, sorted by directory, so that the top level module comes
+// first. Directories that the go tool ignores (vendor, testdata, names starting with . or _) are
+// skipped. When there is a go.work file, the modules that it uses must exist.
+func Discover(root string) ([]Module, error) {
+	found := map[string]Module{}
+	err := filepath.WalkDir(root, func(p string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if p != root && Ignored(name) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != ModFile {
+			return nil
+		}
+		rel, err := filepath.Rel(root, filepath.Dir(p))
+		if err != nil {
+			return err
+		}
+		m, err := load(root, filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		found[m.Dir] = m
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	uses, err := WorkspaceDirs(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range uses {
+		if _, ok := found[dir]; ok {
+			continue
+		}
+		m, err := load(root, dir)
+		if err != nil {
+			return nil, fmt.Errorf("%v uses %q: %v", WorkFile, dir, err)
+		}
+		found[m.Dir] = m
+	}
+	var mods []Module
+	for _, m := range found {
+		mods = append(mods, m)
+	}
+	sort.Slice(mods, func(i, j int) bool {
+		return mods[i].Dir == "." || (mods[j].Dir != "." && mods[i].Dir < mods[j].Dir)
+	})
+	return mods, nil
+}
+
+// WorkspaceDirs returns the directories that go.work at root uses, relative to root, or nil when
+// there is no go.work.
+func WorkspaceDirs(root string) ([]string, error) {
+	fname := filepath.Join(root, WorkFile)
+	data, err := os.ReadFile(fname)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	wf, err := modfile.ParseWork(fname, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, u := range wf.Use {
+		dirs = append(dirs, path.Clean(filepath.ToSlash(u.Path)))
+	}
+	return dirs, nil
+}
+
+func load(root, dir string) (Module, error) {
+	fname := filepath.Join(root, filepath.FromSlash(dir), ModFile)
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return Module{}, err
+	}
+	modPath := modfile.ModulePath(data)
+	if modPath == "" {
+		return Module{}, fmt.Errorf("%v lacks a module directive", path.Join(dir, ModFile))
+	}
+	return Module{Dir: dir, Path: modPath}, nil
+}
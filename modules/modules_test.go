@@ -0,0 +1,208 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		fname := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fname, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestDiscover(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"go.mod":               "module github.com/x/y\n",
+		"tools/go.mod":         "// comment\nmodule github.com/x/y/tools\n\ngo 1.22\n",
+		"tools/v2/go.mod":      "module github.com/x/y/tools/v2\n",
+		"vendor/a/go.mod":      "module github.com/a\n",
+		"testdata/go.mod":      "module github.com/t\n",
+		".hidden/go.mod":       "module github.com/h\n",
+		"outside/lib/go.mod":   "module github.com/x/lib\n",
+		"go.work":              "go 1.22\n\nuse (\n\t.\n\t./tools\n\t./outside/lib\n)\n",
+		"tools/cmd/main.go":    "package main\n",
+		"docs/not-a-module.md": "",
+	})
+	got, err := Discover(root)
+	if err != nil {
+		t.Fatalf("Discover() = _,%v", err)
+	}
+	want := []Module{
+		{Dir: ".", Path: "github.com/x/y"},
+		{Dir: "outside/lib", Path: "github.com/x/lib"},
+		{Dir: "tools", Path: "github.com/x/y/tools"},
+		{Dir: "tools/v2", Path: "github.com/x/y/tools/v2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Discover() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiscoverErrors(t *testing.T) {
+	for _, test := range []struct {
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			files: map[string]string{
+				"go.work": "go 1.22\n\nuse ./missing\n",
+			},
+			wantErr: `go.work uses "missing"`,
+		},
+		{
+			files: map[string]string{
+				"sub/go.mod": "go 1.22\n",
+			},
+			wantErr: "sub/go.mod lacks a module directive",
+		},
+		{
+			files: map[string]string{
+				"go.work": "use (\n",
+			},
+			wantErr: "go.work",
+		},
+	} {
+		root := writeFiles(t, test.files)
+		_, err := Discover(root)
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("Discover(%v) = _,%v, want error with %q", test.files, err, test.wantErr)
+		}
+	}
+}
+
+func TestDiscoverNone(t *testing.T) {
+	got, err := Discover(writeFiles(t, map[string]string{"README.md": ""}))
+	if err != nil || len(got) != 0 {
+		t.Errorf("Discover() = %v,%v, want no modules and no error", got, err)
+	}
+}
+
+func TestTags(t *testing.T) {
+	for _, test := range []struct {
+		mod         Module
+		tagName     string
+		wantPrefix  string
+		wantVersion string
+		wantOK      bool
+	}{
+		{
+			mod:         Module{Dir: ".", Path: "github.com/x/y"},
+			tagName:     "v1.2.3",
+			wantPrefix:  "",
+			wantVersion: "v1.2.3",
+			wantOK:      true,
+		},
+		{
+			mod:        Module{Dir: ".", Path: "github.com/x/y"},
+			tagName:    "tools/v0.3.1",
+			wantPrefix: "",
+			wantOK:     false,
+		},
+		{
+			mod:         Module{Dir: "tools", Path: "github.com/x/y/tools"},
+			tagName:     "tools/v0.3.1",
+			wantPrefix:  "tools/",
+			wantVersion: "v0.3.1",
+			wantOK:      true,
+		},
+		{
+			mod:        Module{Dir: "tools", Path: "github.com/x/y/tools"},
+			tagName:    "v0.3.1",
+			wantPrefix: "tools/",
+			wantOK:     false,
+		},
+		{
+			mod:        Module{Dir: "tools", Path: "github.com/x/y/tools"},
+			tagName:    "tools/sub/v0.3.1",
+			wantPrefix: "tools/",
+			wantOK:     false,
+		},
+		{
+			// major version subdirectory
+			mod:         Module{Dir: "tools/v2", Path: "github.com/x/y/tools/v2"},
+			tagName:     "tools/v2.0.1",
+			wantPrefix:  "tools/",
+			wantVersion: "v2.0.1",
+			wantOK:      true,
+		},
+		{
+			mod:         Module{Dir: "v3", Path: "github.com/x/y/v3"},
+			tagName:     "v3.1.0",
+			wantPrefix:  "",
+			wantVersion: "v3.1.0",
+			wantOK:      true,
+		},
+	} {
+		if got := test.mod.TagPrefix(); got != test.wantPrefix {
+			t.Errorf("%+v .TagPrefix() = %q, want %q", test.mod, got, test.wantPrefix)
+		}
+		version, ok := test.mod.Version(test.tagName)
+		if version != test.wantVersion || ok != test.wantOK {
+			t.Errorf("%+v .Version(%q) = %q,%v, want %q,%v", test.mod, test.tagName, version, ok, test.wantVersion, test.wantOK)
+		}
+		if ok {
+			if got := test.mod.TagName(version); got != test.tagName {
+				t.Errorf("%+v .TagName(%q) = %q, want %q", test.mod, version, got, test.tagName)
+			}
+		}
+	}
+}
+
+func TestOf(t *testing.T) {
+	mods := []Module{
+		{Dir: ".", Path: "example.com/m"},
+		{Dir: "tools", Path: "example.com/m/tools"},
+		{Dir: "tools/gen", Path: "example.com/m/tools/gen"},
+	}
+	for _, test := range []struct {
+		name    string
+		want    string
+		wantRel string
+	}{
+		{name: "a.go", want: ".", wantRel: "a.go"},
+		{name: "toolsx/a.go", want: ".", wantRel: "toolsx/a.go"},
+		{name: "tools/a.go", want: "tools", wantRel: "a.go"},
+		{name: "tools", want: "tools", wantRel: ""},
+		{name: "tools/gen/sub/a.go", want: "tools/gen", wantRel: "sub/a.go"},
+	} {
+		m, ok := Of(mods, test.name)
+		if !ok || m.Dir != test.want {
+			t.Errorf("Of(_,%q) = %+v,%v, want dir %q", test.name, m, ok, test.want)
+			continue
+		}
+		if got := m.Rel(test.name); got != test.wantRel {
+			t.Errorf("%+v .Rel(%q) = %q, want %q", m, test.name, got, test.wantRel)
+		}
+	}
+	if m, ok := Of(mods[1:], "a.go"); ok {
+		t.Errorf("Of(nested only, a.go) = %+v,true, want false", m)
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	for dir, want := range map[string]bool{
+		"vendor":    true,
+		"testdata":  true,
+		".git":      true,
+		"_examples": true,
+		"tools":     false,
+		"data":      false,
+	} {
+		if got := Ignored(dir); got != want {
+			t.Errorf("Ignored(%q) = %v, want %v", dir, got, want)
+		}
+	}
+}
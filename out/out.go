@@ -7,10 +7,37 @@ import (
 	"github.com/mitchellh/colorstring"
 )
 
+var (
+	// When recording, messages are collected instead of shown
+	recording bool
+	recorded  []string
+)
+
+// Record suppresses all output, messages are collected instead and available using Recorded.
+func Record() {
+	recording = true
+}
+
+// Recorded returns the messages that were collected since the previous call.
+func Recorded() []string {
+	r := recorded
+	recorded = nil
+	return r
+}
+
 func out(col, msg string) {
-	if msg != "" {
-		colorstring.Printf(fmt.Sprintf("[gogit] [%v]%v\n", col, msg))
+	if msg == "" {
+		return
+	}
+	if recording {
+		recorded = append(recorded, msg)
+		return
 	}
+	// The message is neither a format nor colorized, it may contain %-signs or brackets.
+	c := colorstring.Colorize{
+		Colors: colorstring.DefaultColors,
+	}
+	fmt.Println(c.Color(fmt.Sprintf("[gogit] [%v]", col)) + msg + c.Color("[reset]"))
 }
 
 func Error(ss ...string) {
@@ -21,6 +48,14 @@ func Error(ss ...string) {
 	}
 }
 
+func Warn(ss ...string) {
+	for _, s := range ss {
+		for _, l := range strings.Split(s, "\n") {
+			out("light_red", l)
+		}
+	}
+}
+
 func Title(ss ...string) {
 	for _, s := range ss {
 		for _, l := range strings.Split(s, "\n") {
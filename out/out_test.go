@@ -1,9 +1,23 @@
 package out
 
 import (
+	"strings"
 	"testing"
 )
 
-func TestAll(t *testing.T) {
-	// TODO: add tests
+func TestRecord(t *testing.T) {
+	Record()
+	defer func() { recording = false }()
+
+	Title("title")
+	Msg("100%% %v", "done")
+	Error("first\nsecond")
+	Warn("")
+	want := []string{"title", "100% done", "first", "second"}
+	if got := Recorded(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Recorded() = %q, want %q", got, want)
+	}
+	if got := Recorded(); got != nil {
+		t.Errorf("Recorded() again = %q, want nil", got)
+	}
 }
@@ -0,0 +1,33 @@
+// Package position parses the file:line:column positions that the go tools print.
+package position
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Split splits a position such as "a.go:3:5" or "a.go:3" into its parts. Unknown parts are left
+// empty.
+func Split(pos string) (file string, line, col int) {
+	parts := strings.Split(pos, ":")
+	nums := []int{}
+	for len(parts) > 1 && len(nums) < 2 {
+		n, err := strconv.Atoi(parts[len(parts)-1])
+		if err != nil {
+			break
+		}
+		nums = append([]int{n}, nums...)
+		parts = parts[:len(parts)-1]
+	}
+	file = strings.Join(parts, ":")
+	if file == "-" {
+		file = ""
+	}
+	switch len(nums) {
+	case 2:
+		return file, nums[0], nums[1]
+	case 1:
+		return file, nums[0], 0
+	}
+	return file, 0, 0
+}
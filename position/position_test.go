@@ -0,0 +1,25 @@
+package position
+
+import "testing"
+
+func TestSplit(t *testing.T) {
+	for _, test := range []struct {
+		pos      string
+		wantFile string
+		wantLine int
+		wantCol  int
+	}{
+		{pos: "a.go:3:5", wantFile: "a.go", wantLine: 3, wantCol: 5},
+		{pos: "a.go:3", wantFile: "a.go", wantLine: 3},
+		{pos: "a.go", wantFile: "a.go"},
+		{pos: "C:/x/a.go:3:5", wantFile: "C:/x/a.go", wantLine: 3, wantCol: 5},
+		{pos: "-", wantFile: ""},
+		{pos: "", wantFile: ""},
+	} {
+		file, line, col := Split(test.pos)
+		if file != test.wantFile || line != test.wantLine || col != test.wantCol {
+			t.Errorf("Split(%q) = %q,%v,%v, want %q,%v,%v", test.pos, file, line, col,
+				test.wantFile, test.wantLine, test.wantCol)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package prepush parses what git hands to a pre-push hook: the name and URL of the remote as
+// arguments, and on stdin one line per pushed ref, as in
+// `<local ref> <local sha> <remote ref> <remote sha>`.
+package prepush
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const tagPrefix = "refs/tags/"
+
+// Ref is one line of the pre-push input.
+type Ref struct {
+	LocalRef, LocalSHA, RemoteRef, RemoteSHA string
+}
+
+// Push is the complete pre-push input.
+type Push struct {
+	Remote, URL string
+	Refs        []Ref
+}
+
+// Parse parses the arguments of the hook (remote name and URL) and its stdin.
+func Parse(args []string, r io.Reader) (*Push, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("pre-push expects 2 arguments (remote name and URL), got %v", args)
+	}
+	p := &Push{
+		Remote: args[0],
+		URL:    args[1],
+	}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		parts := strings.Fields(line)
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("pre-push input line %q doesn't have 4 parts", line)
+		}
+		p.Refs = append(p.Refs, Ref{
+			LocalRef:  parts[0],
+			LocalSHA:  parts[1],
+			RemoteRef: parts[2],
+			RemoteSHA: parts[3],
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pre-push input: %v", err)
+	}
+	return p, nil
+}
+
+// IsDelete is true when the ref is deleted from the remote.
+func (r Ref) IsDelete() bool {
+	return isZero(r.LocalSHA)
+}
+
+// IsNew is true when the remote doesn't have the ref yet.
+func (r Ref) IsNew() bool {
+	return isZero(r.RemoteSHA)
+}
+
+// Tag returns the name of the pushed tag, or "" when the ref isn't a tag.
+func (r Ref) Tag() string {
+	if !strings.HasPrefix(r.RemoteRef, tagPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(r.RemoteRef, tagPrefix)
+}
+
+// OnlyDeletes is true when the push deletes refs, and nothing else.
+func (p *Push) OnlyDeletes() bool {
+	if len(p.Refs) == 0 {
+		return false
+	}
+	for _, r := range p.Refs {
+		if !r.IsDelete() {
+			return false
+		}
+	}
+	return true
+}
+
+// Tags returns the refs that push (not delete) tags.
+func (p *Push) Tags() []Ref {
+	var refs []Ref
+	for _, r := range p.Refs {
+		if r.Tag() != "" && !r.IsDelete() {
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+// Both SHA-1 and SHA-256 repositories use all zeros for an absent object.
+func isZero(sha string) bool {
+	return strings.Trim(sha, "0") == ""
+}
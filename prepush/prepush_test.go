@@ -0,0 +1,135 @@
+package prepush
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	sha1 = "1111111111111111111111111111111111111111"
+	sha2 = "2222222222222222222222222222222222222222"
+	zero = "0000000000000000000000000000000000000000"
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		args            []string
+		stdin           string
+		wantErr         string
+		wantRefs        int
+		wantOnlyDeletes bool
+		wantTags        []string
+	}{
+		{
+			args:    []string{"origin"},
+			stdin:   "",
+			wantErr: "expects 2 arguments",
+		},
+		{
+			args:    []string{"origin", "git@github.com:x/y.git"},
+			stdin:   "refs/heads/main " + sha1 + " refs/heads/main\n",
+			wantErr: "doesn't have 4 parts",
+		},
+		{
+			args:            []string{"origin", "git@github.com:x/y.git"},
+			stdin:           "",
+			wantRefs:        0,
+			wantOnlyDeletes: false,
+			wantTags:        nil,
+		},
+		{
+			args:            []string{"origin", "git@github.com:x/y.git"},
+			stdin:           "refs/heads/main " + sha1 + " refs/heads/main " + sha2 + "\n\n",
+			wantRefs:        1,
+			wantOnlyDeletes: false,
+			wantTags:        nil,
+		},
+		{
+			args:            []string{"origin", "git@github.com:x/y.git"},
+			stdin:           "(delete) " + zero + " refs/heads/topic " + sha2 + "\n",
+			wantRefs:        1,
+			wantOnlyDeletes: true,
+			wantTags:        nil,
+		},
+		{
+			args: []string{"origin", "git@github.com:x/y.git"},
+			stdin: strings.Join([]string{
+				"refs/heads/main " + sha1 + " refs/heads/main " + sha2,
+				"refs/tags/v1.2.3 " + sha1 + " refs/tags/v1.2.3 " + zero,
+				"(delete) " + zero + " refs/tags/v1.2.2 " + sha2,
+			}, "\n"),
+			wantRefs:        3,
+			wantOnlyDeletes: false,
+			wantTags:        []string{"v1.2.3"},
+		},
+	} {
+		p, err := Parse(test.args, strings.NewReader(test.stdin))
+		switch {
+		case err == nil && test.wantErr != "":
+			t.Errorf("Parse(%v,%q) = _,nil, want error with %q", test.args, test.stdin, test.wantErr)
+			continue
+		case err != nil && test.wantErr == "":
+			t.Errorf("Parse(%v,%q) = _,%q, want nil error", test.args, test.stdin, err.Error())
+			continue
+		case err != nil && !strings.Contains(err.Error(), test.wantErr):
+			t.Errorf("Parse(%v,%q) = _,%q, want error with %q", test.args, test.stdin, err.Error(), test.wantErr)
+			continue
+		case err != nil:
+			continue
+		}
+		if p.Remote != test.args[0] || p.URL != test.args[1] {
+			t.Errorf("Parse(%v,_) = %+v, want remote %q and URL %q", test.args, p, test.args[0], test.args[1])
+		}
+		if len(p.Refs) != test.wantRefs {
+			t.Errorf("Parse(_,%q): %v refs, want %v", test.stdin, len(p.Refs), test.wantRefs)
+		}
+		if got := p.OnlyDeletes(); got != test.wantOnlyDeletes {
+			t.Errorf("Parse(_,%q).OnlyDeletes() = %v, want %v", test.stdin, got, test.wantOnlyDeletes)
+		}
+		var gotTags []string
+		for _, r := range p.Tags() {
+			gotTags = append(gotTags, r.Tag())
+		}
+		if strings.Join(gotTags, ",") != strings.Join(test.wantTags, ",") {
+			t.Errorf("Parse(_,%q).Tags() = %v, want %v", test.stdin, gotTags, test.wantTags)
+		}
+	}
+}
+
+func TestRef(t *testing.T) {
+	for _, test := range []struct {
+		ref          Ref
+		wantIsDelete bool
+		wantIsNew    bool
+		wantTag      string
+	}{
+		{
+			ref:          Ref{"refs/heads/main", sha1, "refs/heads/main", sha2},
+			wantIsDelete: false,
+			wantIsNew:    false,
+			wantTag:      "",
+		},
+		{
+			ref:          Ref{"refs/tags/v0.1.0", sha1, "refs/tags/v0.1.0", zero},
+			wantIsDelete: false,
+			wantIsNew:    true,
+			wantTag:      "v0.1.0",
+		},
+		{
+			ref:          Ref{"(delete)", strings.Repeat("0", 64), "refs/tags/v0.1.0", sha2},
+			wantIsDelete: true,
+			wantIsNew:    false,
+			wantTag:      "v0.1.0",
+		},
+	} {
+		if got := test.ref.IsDelete(); got != test.wantIsDelete {
+			t.Errorf("%+v .IsDelete() = %v, want %v", test.ref, got, test.wantIsDelete)
+		}
+		if got := test.ref.IsNew(); got != test.wantIsNew {
+			t.Errorf("%+v .IsNew() = %v, want %v", test.ref, got, test.wantIsNew)
+		}
+		if got := test.ref.Tag(); got != test.wantTag {
+			t.Errorf("%+v .Tag() = %q, want %q", test.ref, got, test.wantTag)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package report gathers the results of a gogit run into one machine-readable document: per
+// check its status, duration, messages, findings and suggestions.
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/KarelKubat/gogit/action"
+	"github.com/KarelKubat/gogit/errs"
+)
+
+// Statuses of checks and of the run as a whole.
+const (
+	StatusOK      = "ok"
+	StatusWarning = "warning"
+	StatusError   = "error"
+	StatusSkipped = "skipped"
+)
+
+type Finding struct {
+	Severity    string   `json:"severity"`
+	File        string   `json:"file,omitempty"`
+	Line        int      `json:"line,omitempty"`
+	Column      int      `json:"column,omitempty"`
+	Message     string   `json:"message"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+type Suggestion struct {
+	Command string `json:"command"`
+	Safe    bool   `json:"safe"`
+}
+
+type Check struct {
+	Name        string       `json:"name"`
+	Status      string       `json:"status"`
+	Summary     string       `json:"summary"`
+	DurationMS  int64        `json:"duration_ms"`
+	Messages    []string     `json:"messages"`
+	Findings    []Finding    `json:"findings"`
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+type Report struct {
+	Tool   string   `json:"tool"`
+	Action string   `json:"action"`
+	Status string   `json:"status"`
+	Error  string   `json:"error,omitempty"`
+	Checks []*Check `json:"checks"`
+}
+
+func New(act string) *Report {
+	return &Report{
+		Tool:   "gogit",
+		Action: act,
+		Status: StatusOK,
+		Checks: []*Check{},
+	}
+}
+
+// Add records the outcome of a check.
+func (r *Report) Add(fs *errs.Findings, d time.Duration, messages []string, suggestions []action.Suggestion) *Check {
+	c := &Check{
+		Name:        fs.Check,
+		Status:      StatusOK,
+		Summary:     fs.Summary(),
+		DurationMS:  d.Milliseconds(),
+		Messages:    []string{},
+		Findings:    []Finding{},
+		Suggestions: []Suggestion{},
+	}
+	switch {
+	case fs.Failed():
+		c.Status = StatusError
+	case fs.Count(errs.Warn) > 0:
+		c.Status = StatusWarning
+	}
+	c.Messages = append(c.Messages, messages...)
+	for _, f := range fs.List {
+		c.Findings = append(c.Findings, Finding{
+			Severity:    f.Severity.String(),
+			File:        f.File,
+			Line:        f.Line,
+			Column:      f.Column,
+			Message:     f.Msg,
+			Suggestions: f.Suggestions,
+		})
+	}
+	for _, s := range suggestions {
+		c.Suggestions = append(c.Suggestions, Suggestion{
+			Command: s.Cmd,
+			Safe:    s.Safe,
+		})
+	}
+	r.Checks = append(r.Checks, c)
+	r.worsen(c.Status)
+	return c
+}
+
+// Skip records a check that didn't run because an earlier one failed.
+func (r *Report) Skip(name string) {
+	r.Checks = append(r.Checks, &Check{
+		Name:        name,
+		Status:      StatusSkipped,
+		Summary:     StatusSkipped,
+		Messages:    []string{},
+		Findings:    []Finding{},
+		Suggestions: []Suggestion{},
+	})
+}
+
+// Fail records an error that prevented checks from running at all.
+func (r *Report) Fail(err error) {
+	r.Error = err.Error()
+	r.Status = StatusError
+}
+
+func (r *Report) worsen(status string) {
+	switch {
+	case status == StatusError:
+		r.Status = StatusError
+	case status == StatusWarning && r.Status == StatusOK:
+		r.Status = StatusWarning
+	}
+}
+
+// WriteJSON writes the report as an indented JSON document.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(r)
+}
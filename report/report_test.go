@@ -0,0 +1,99 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KarelKubat/gogit/action"
+	"github.com/KarelKubat/gogit/errs"
+)
+
+func TestStatus(t *testing.T) {
+	for _, test := range []struct {
+		severities []errs.Severity
+		wantCheck  string
+		wantReport string
+	}{
+		{
+			severities: nil,
+			wantCheck:  StatusOK,
+			wantReport: StatusOK,
+		},
+		{
+			severities: []errs.Severity{errs.Info},
+			wantCheck:  StatusOK,
+			wantReport: StatusOK,
+		},
+		{
+			severities: []errs.Severity{errs.Info, errs.Warn},
+			wantCheck:  StatusWarning,
+			wantReport: StatusWarning,
+		},
+		{
+			severities: []errs.Severity{errs.Error, errs.Warn},
+			wantCheck:  StatusError,
+			wantReport: StatusError,
+		},
+	} {
+		r := New("pre-commit")
+		fs := errs.New("check")
+		for _, sev := range test.severities {
+			fs.Add(sev, "msg")
+		}
+		c := r.Add(fs, time.Second, nil, nil)
+		if c.Status != test.wantCheck {
+			t.Errorf("findings %v: check status %q, want %q", test.severities, c.Status, test.wantCheck)
+		}
+		if r.Status != test.wantReport {
+			t.Errorf("findings %v: report status %q, want %q", test.severities, r.Status, test.wantReport)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	r := New("pre-push")
+	fs := errs.New("gotests")
+	fs.Error("go source lacks a test", "gogit make-test-frame a.go").At("a.go", 0)
+	r.Add(fs, 1500*time.Millisecond, []string{"checking for go tests"},
+		[]action.Suggestion{{Cmd: "gogit make-test-frame a.go", Safe: true}})
+	r.Skip("govets")
+
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() = %v, want nil error", err)
+	}
+	var got Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("WriteJSON() wrote invalid JSON %q: %v", buf.String(), err)
+	}
+	if got.Tool != "gogit" || got.Action != "pre-push" || got.Status != StatusError {
+		t.Errorf("WriteJSON(): got tool/action/status %q/%q/%q, want gogit/pre-push/error", got.Tool, got.Action, got.Status)
+	}
+	if len(got.Checks) != 2 {
+		t.Fatalf("WriteJSON(): got %v checks, want 2", len(got.Checks))
+	}
+	c := got.Checks[0]
+	if c.Name != "gotests" || c.DurationMS != 1500 || len(c.Messages) != 1 {
+		t.Errorf("WriteJSON(): got check %+v, want gotests taking 1500ms with 1 message", c)
+	}
+	if len(c.Findings) != 1 || c.Findings[0].File != "a.go" || c.Findings[0].Severity != "error" {
+		t.Errorf("WriteJSON(): got findings %+v, want 1 error in a.go", c.Findings)
+	}
+	if len(c.Suggestions) != 1 || !c.Suggestions[0].Safe {
+		t.Errorf("WriteJSON(): got suggestions %+v, want 1 safe one", c.Suggestions)
+	}
+	if got.Checks[1].Status != StatusSkipped {
+		t.Errorf("WriteJSON(): got second check status %q, want %q", got.Checks[1].Status, StatusSkipped)
+	}
+}
+
+func TestFail(t *testing.T) {
+	r := New("hooks")
+	r.Fail(errors.New("not a git repository"))
+	if r.Status != StatusError || r.Error != "not a git repository" {
+		t.Errorf("Fail(): got status %q and error %q, want error status and message", r.Status, r.Error)
+	}
+}
@@ -0,0 +1,180 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// SARIF 2.1.0, the format that GitHub code scanning accepts. Only the parts that gogit needs
+// are modeled.
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolURI      = "https://github.com/KarelKubat/gogit"
+
+	// Locations are relative to the top level of the repository.
+	srcRoot = "%SRCROOT%"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+// sarifNotification is a finding without a location, which code scanning doesn't accept as a
+// result, or the error that prevented the checks from running, which belongs to no rule.
+type sarifNotification struct {
+	Level          string                    `json:"level"`
+	Message        sarifMessage              `json:"message"`
+	AssociatedRule *sarifReportingDescriptor `json:"associatedRule,omitempty"`
+}
+
+type sarifReportingDescriptor struct {
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifLevels maps the severity of findings onto SARIF levels.
+var sarifLevels = map[string]string{
+	"error":   "error",
+	"warning": "warning",
+	"info":    "note",
+}
+
+// WriteSARIF writes the report as a SARIF log with one run. Each check that ran is a rule, each
+// finding with a file is a result; findings without one are notifications of the invocation, as
+// code scanning rejects results without a location. So is the error of a run that failed before
+// any check. Suggestions are appended to the message.
+func (r *Report) WriteSARIF(w io.Writer) error {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           r.Tool,
+				InformationURI: toolURI,
+				Rules:          []sarifRule{},
+			},
+		},
+		Invocations: []sarifInvocation{{ExecutionSuccessful: r.Error == ""}},
+		Results:     []sarifResult{},
+	}
+	if r.Error != "" {
+		run.Invocations[0].ToolExecutionNotifications = append(run.Invocations[0].ToolExecutionNotifications,
+			sarifNotification{Level: "error", Message: sarifMessage{Text: r.Error}})
+	}
+	for _, c := range r.Checks {
+		if c.Status == StatusSkipped {
+			continue
+		}
+		ruleIndex := len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               c.Name,
+			Name:             c.Name,
+			ShortDescription: sarifMessage{Text: "gogit check " + c.Name},
+		})
+		for _, f := range c.Findings {
+			text := f.Message
+			if len(f.Suggestions) > 0 {
+				text += "\n" + strings.Join(f.Suggestions, "\n")
+			}
+			if f.File == "" {
+				run.Invocations[0].ToolExecutionNotifications = append(run.Invocations[0].ToolExecutionNotifications,
+					sarifNotification{
+						Level:          sarifLevels[f.Severity],
+						Message:        sarifMessage{Text: text},
+						AssociatedRule: &sarifReportingDescriptor{ID: c.Name, Index: ruleIndex},
+					})
+				continue
+			}
+			loc := sarifLocation{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{
+						URI:       filepath.ToSlash(f.File),
+						URIBaseID: srcRoot,
+					},
+				},
+			}
+			if f.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{
+					StartLine:   f.Line,
+					StartColumn: f.Column,
+				}
+			}
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    c.Name,
+				RuleIndex: ruleIndex,
+				Level:     sarifLevels[f.Severity],
+				Message:   sarifMessage{Text: text},
+				Locations: []sarifLocation{loc},
+			})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,155 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KarelKubat/gogit/errs"
+)
+
+// validateSARIF checks the parts of the SARIF 2.1.0 schema that gogit produces: required
+// properties, types and allowed values.
+func validateSARIF(t *testing.T, doc map[string]any) {
+	t.Helper()
+	if v, ok := doc["version"].(string); !ok || v != "2.1.0" {
+		t.Errorf("version = %v, want 2.1.0", doc["version"])
+	}
+	if _, ok := doc["$schema"].(string); !ok {
+		t.Errorf("$schema = %v, want a string", doc["$schema"])
+	}
+	runs, ok := doc["runs"].([]any)
+	if !ok || len(runs) == 0 {
+		t.Fatalf("runs = %v, want a non-empty array", doc["runs"])
+	}
+	for _, r := range runs {
+		run := r.(map[string]any)
+		driver, ok := run["tool"].(map[string]any)["driver"].(map[string]any)
+		if !ok {
+			t.Fatalf("run.tool.driver missing in %v", run)
+		}
+		if name, ok := driver["name"].(string); !ok || name == "" {
+			t.Errorf("run.tool.driver.name = %v, want a non-empty string", driver["name"])
+		}
+		ruleIDs := map[string]bool{}
+		for _, rl := range driver["rules"].([]any) {
+			id, ok := rl.(map[string]any)["id"].(string)
+			if !ok || id == "" {
+				t.Errorf("rule %v lacks an id", rl)
+			}
+			ruleIDs[id] = true
+		}
+		results, ok := run["results"].([]any)
+		if !ok {
+			t.Fatalf("run.results = %v, want an array", run["results"])
+		}
+		for _, rs := range results {
+			res := rs.(map[string]any)
+			if text, ok := res["message"].(map[string]any)["text"].(string); !ok || text == "" {
+				t.Errorf("result %v lacks message.text", res)
+			}
+			if id, _ := res["ruleId"].(string); !ruleIDs[id] {
+				t.Errorf("result %v refers to unknown rule %q", res, id)
+			}
+			switch res["level"] {
+			case "none", "note", "warning", "error":
+			default:
+				t.Errorf("result %v has invalid level %v", res, res["level"])
+			}
+			locs, _ := res["locations"].([]any)
+			if len(locs) == 0 {
+				t.Errorf("result %v has no locations, which code scanning rejects", res)
+			}
+			for _, l := range locs {
+				phys := l.(map[string]any)["physicalLocation"].(map[string]any)
+				if uri, ok := phys["artifactLocation"].(map[string]any)["uri"].(string); !ok || uri == "" {
+					t.Errorf("location %v lacks artifactLocation.uri", l)
+				}
+				if region, ok := phys["region"].(map[string]any); ok {
+					if line, ok := region["startLine"].(float64); !ok || line < 1 {
+						t.Errorf("location %v has invalid region.startLine", l)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	r := New("pre-commit")
+	vets := errs.New("govets")
+	vets.Error("fmt.Printf format %d has arg x of wrong type string").At("a.go", 5).WithColumn(24)
+	vets.Warn("something without a line").At("README.md", 0)
+	vets.Info("something without a location", "gogit make-test-frame a.go")
+	r.Add(vets, time.Second, nil, nil)
+	r.Add(errs.New("stdfiles"), time.Second, nil, nil)
+	r.Skip("mdtoc")
+
+	var buf bytes.Buffer
+	if err := r.WriteSARIF(&buf); err != nil {
+		t.Fatalf("WriteSARIF() = %v, want nil error", err)
+	}
+	doc := map[string]any{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteSARIF() wrote invalid JSON %q: %v", buf.String(), err)
+	}
+	validateSARIF(t, doc)
+
+	run := doc["runs"].([]any)[0].(map[string]any)
+	if n := len(run["tool"].(map[string]any)["driver"].(map[string]any)["rules"].([]any)); n != 2 {
+		t.Errorf("got %v rules, want 2 (skipped checks aren't rules)", n)
+	}
+	results := run["results"].([]any)
+	if len(results) != 2 {
+		t.Fatalf("got %v results, want 2", len(results))
+	}
+	first := results[0].(map[string]any)
+	region := first["locations"].([]any)[0].(map[string]any)["physicalLocation"].(map[string]any)["region"].(map[string]any)
+	if region["startLine"] != 5.0 || region["startColumn"] != 24.0 {
+		t.Errorf("first result region = %v, want line 5, column 24", region)
+	}
+	notes, _ := run["invocations"].([]any)[0].(map[string]any)["toolExecutionNotifications"].([]any)
+	if len(notes) != 1 {
+		t.Fatalf("got notifications %v, want 1 for the finding without a file", notes)
+	}
+	note := notes[0].(map[string]any)
+	if note["level"] != "note" || note["associatedRule"].(map[string]any)["id"] != "govets" {
+		t.Errorf("notification = %v, want level note for rule govets", note)
+	}
+	if text := note["message"].(map[string]any)["text"]; text != "something without a location\ngogit make-test-frame a.go" {
+		t.Errorf("notification text = %q, want the message and the suggestion", text)
+	}
+}
+
+func TestWriteSARIFFail(t *testing.T) {
+	r := New("pre-push")
+	r.Fail(errors.New("not a git repository"))
+
+	var buf bytes.Buffer
+	if err := r.WriteSARIF(&buf); err != nil {
+		t.Fatalf("WriteSARIF() = %v, want nil error", err)
+	}
+	doc := map[string]any{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteSARIF() wrote invalid JSON %q: %v", buf.String(), err)
+	}
+	validateSARIF(t, doc)
+
+	inv := doc["runs"].([]any)[0].(map[string]any)["invocations"].([]any)[0].(map[string]any)
+	if inv["executionSuccessful"] != false {
+		t.Errorf("executionSuccessful = %v, want false", inv["executionSuccessful"])
+	}
+	notes, _ := inv["toolExecutionNotifications"].([]any)
+	if len(notes) != 1 {
+		t.Fatalf("got notifications %v, want 1 with the error", notes)
+	}
+	note := notes[0].(map[string]any)
+	if note["level"] != "error" || note["message"].(map[string]any)["text"] != "not a git repository" {
+		t.Errorf("notification = %v, want level error with the message of the failure", note)
+	}
+	if _, ok := note["associatedRule"]; ok {
+		t.Errorf("notification = %v, want no associated rule", note)
+	}
+}
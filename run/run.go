@@ -1,7 +1,6 @@
 package run
 
 import (
-	"fmt"
 	"os/exec"
 	"strings"
 
@@ -12,13 +11,40 @@ var cache = make(map[string][]string)
 
 // Exec runs a command and returns its output, unless it was run before, then its cached results are returned.
 func Exec(title string, cmd []string) ([]string, error) {
+	return cached(title, cmd, true)
+}
+
+// Capture is like Exec, but when the command fails, its output isn't shown: the caller reports
+// it, e.g. as the diff of a finding.
+func Capture(title string, cmd []string) ([]string, error) {
+	return cached(title, cmd, false)
+}
+
+func cached(title string, cmd []string, echo bool) ([]string, error) {
 	cli := strings.Join(cmd, " ")
 	if cached, ok := cache[cli]; ok {
 		return cached, nil
 	}
 
+	lines, err := execute(title, cmd, echo)
+	cache[cli] = lines
+	return lines, err
+}
+
+// Shell runs a commandline using `sh -c` and returns its output. The results are not cached, as
+// shell commands are expected to modify things.
+func Shell(title string, cli string) ([]string, error) {
+	return execute(title, []string{"sh", "-c", cli}, true)
+}
+
+// Forget clears the cache, so that commands are re-run, e.g. after fixing something.
+func Forget() {
+	cache = make(map[string][]string)
+}
+
+func execute(title string, cmd []string, echo bool) ([]string, error) {
 	out.Title(title)
-	out.Msg(fmt.Sprintf("running %v", cli))
+	out.Msg("running %v", strings.Join(cmd, " "))
 	b, err := exec.Command(cmd[0], cmd[1:]...).CombinedOutput()
 	lines := []string{}
 	for _, l := range strings.Split(string(b), "\n") {
@@ -26,12 +52,11 @@ func Exec(title string, cmd []string) ([]string, error) {
 			lines = append(lines, l)
 		}
 	}
-	if err != nil {
+	if err != nil && echo {
 		out.Error("output:")
 		for _, l := range lines {
 			out.Error(l)
 		}
 	}
-	cache[cli] = lines
 	return lines, err
 }
@@ -1,7 +1,10 @@
 package run
 
 import (
+	"slices"
 	"testing"
+
+	"github.com/KarelKubat/gogit/out"
 )
 
 func TestExec(t *testing.T) {
@@ -28,3 +31,44 @@ func TestExec(t *testing.T) {
 		}
 	}
 }
+
+func TestShellForget(t *testing.T) {
+	Exec("", []string{"true"})
+	lines, err := Shell("", "echo a; echo b")
+	if err != nil {
+		t.Fatalf("Shell(_,%q) = _,%q, want nil error", "echo a; echo b", err.Error())
+	}
+	if len(lines) != 2 || lines[0] != "a" || lines[1] != "b" {
+		t.Errorf("Shell(_,%q) = %v, want [a b]", "echo a; echo b", lines)
+	}
+	if _, err := Shell("", "exit 1"); err == nil {
+		t.Errorf("Shell(_,%q) = _,nil, want error", "exit 1")
+	}
+	Forget()
+	if gotCacheSize := len(cache); gotCacheSize != 0 {
+		t.Errorf("after Forget(): len(cache) = %v, want 0", gotCacheSize)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	out.Record()
+	out.Recorded()
+	for _, test := range []struct {
+		fn       func(string, []string) ([]string, error)
+		name     string
+		wantEcho bool
+	}{
+		{fn: Exec, name: "Exec", wantEcho: true},
+		{fn: Capture, name: "Capture", wantEcho: false},
+	} {
+		Forget()
+		cmd := []string{"sh", "-c", "echo diff-line; exit 1"}
+		lines, err := test.fn("", cmd)
+		if err == nil || len(lines) != 1 || lines[0] != "diff-line" {
+			t.Errorf("%v(_,%v) = %v,%v, want [diff-line],error", test.name, cmd, lines, err)
+		}
+		if echoed := slices.Contains(out.Recorded(), "diff-line"); echoed != test.wantEcho {
+			t.Errorf("%v(_,%v) echoed the output: %v, want %v", test.name, cmd, echoed, test.wantEcho)
+		}
+	}
+}
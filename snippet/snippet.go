@@ -0,0 +1,204 @@
+// Package snippet keeps code blocks in Markdown documents in sync with the Go sources that they
+// are copied from. An embed directive on the line above a fenced code block names a source and a
+// declaration in it:
+//
+//	<!-- embed: gogit.go /func mdUntab/ -->
+//
+// The declaration is found by parsing the source, so the block follows it when it moves.
+package snippet
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/KarelKubat/gogit/markdown"
+)
+
+// Directive is an embed directive and the fenced code block that it applies to.
+type Directive struct {
+	File   string          // Go source, relative to the document or, with a leading /, to the top level
+	Symbol string          // e.g. "F", "T.M", or /regexp/ matching the first line of the declaration
+	Line   int             // 1-based line of the directive
+	Fence  *markdown.Fence // the block below the directive, nil when there is none
+}
+
+var directiveRe = regexp.MustCompile(`^\s*<!--\s*embed:\s*(\S+)\s+(.*?)\s*-->\s*$`)
+
+// Directives returns the embed directives of a document, outside fenced code blocks.
+func Directives(content string) []Directive {
+	lines := strings.Split(content, "\n")
+	in := markdown.InFence(content)
+	fences := map[int]markdown.Fence{}
+	for _, f := range markdown.Fences(content) {
+		fences[f.Start] = f
+	}
+	var ds []Directive
+	for i, l := range lines {
+		m := directiveRe.FindStringSubmatch(l)
+		if m == nil || in[i] {
+			continue
+		}
+		d := Directive{File: m[1], Symbol: m[2], Line: i + 1}
+		next := i + 1
+		for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+			next++
+		}
+		if f, ok := fences[next+1]; ok {
+			d.Fence = &f
+		}
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+// Path returns the path of the source of a directive in document doc.
+func (d Directive) Path(doc string) string {
+	if strings.HasPrefix(d.File, "/") {
+		return path.Clean(strings.TrimPrefix(d.File, "/"))
+	}
+	return path.Join(path.Dir(doc), d.File)
+}
+
+// Extract returns the lines of the top-level declaration in a Go source that symbol names: a
+// function, a method as "T.M", or a type, constant or variable. A symbol between slashes is a
+// regular expression that must match the first line of exactly one declaration. Leading tabs are
+// replaced by spaces, like gogit does in code blocks.
+func Extract(src []byte, symbol string) ([]string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	var re *regexp.Regexp
+	if len(symbol) > 2 && strings.HasPrefix(symbol, "/") && strings.HasSuffix(symbol, "/") {
+		if re, err = regexp.Compile(symbol[1 : len(symbol)-1]); err != nil {
+			return nil, fmt.Errorf("bad symbol %v: %v", symbol, err)
+		}
+	}
+	var found []string
+	var at []int
+	for _, decl := range f.Decls {
+		text := string(src[fset.Position(decl.Pos()).Offset:fset.Position(decl.End()).Offset])
+		first, _, _ := strings.Cut(text, "\n")
+		if (re != nil && re.MatchString(first)) || (re == nil && declares(decl, symbol)) {
+			found = append(found, text)
+			at = append(at, fset.Position(decl.Pos()).Line)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no declaration of %v", symbol)
+	case 1:
+	default:
+		return nil, fmt.Errorf("%v matches %v declarations, at lines %v", symbol, len(found), at)
+	}
+	lines := strings.Split(found[0], "\n")
+	for i, l := range lines {
+		lines[i] = markdown.Untab(l)
+	}
+	return lines, nil
+}
+
+// declares returns true when a declaration declares name.
+func declares(decl ast.Decl, name string) bool {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil || len(d.Recv.List) == 0 {
+			return d.Name.Name == name
+		}
+		return receiver(d.Recv.List[0].Type)+"."+d.Name.Name == name
+	case *ast.GenDecl:
+		for _, s := range d.Specs {
+			switch s := s.(type) {
+			case *ast.TypeSpec:
+				if s.Name.Name == name {
+					return true
+				}
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					if n.Name == name {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// receiver returns the type name of a receiver, e.g. "T" for *T or T[K].
+func receiver(e ast.Expr) string {
+	switch t := e.(type) {
+	case *ast.StarExpr:
+		return receiver(t.X)
+	case *ast.IndexExpr:
+		return receiver(t.X)
+	case *ast.IndexListExpr:
+		return receiver(t.X)
+	case *ast.ParenExpr:
+		return receiver(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// Problem is an embed directive that can't be resolved.
+type Problem struct {
+	Line int // 1-based line of the directive
+	Msg  string
+}
+
+// Refresh returns the content of document doc with the code blocks of its embed directives
+// replaced by the declarations that they name, and the directives that can't be resolved, which
+// are left alone.
+func Refresh(doc, content string) (string, []Problem) {
+	lines := strings.Split(content, "\n")
+	var problems []Problem
+	ds := Directives(content)
+	blocks := make([][]string, len(ds))
+	for i, d := range ds {
+		switch {
+		case d.Fence == nil:
+			problems = append(problems, Problem{d.Line, "embed directive isn't followed by a fenced code block"})
+			continue
+		case d.Fence.End == 0:
+			problems = append(problems, Problem{d.Line, fmt.Sprintf("code block at line %v isn't closed", d.Fence.Start)})
+			continue
+		}
+		src, err := os.ReadFile(d.Path(doc))
+		if err != nil {
+			problems = append(problems, Problem{d.Line, err.Error()})
+			continue
+		}
+		block, err := Extract(src, d.Symbol)
+		if err != nil {
+			problems = append(problems, Problem{d.Line, fmt.Sprintf("%v: %v", d.Path(doc), err)})
+			continue
+		}
+		indent := strings.Repeat(" ", d.Fence.Indent)
+		for j, l := range block {
+			if l != "" {
+				block[j] = indent + l
+			}
+		}
+		blocks[i] = block
+	}
+
+	// Replace from the bottom up, so that the line numbers of earlier blocks stay valid.
+	for i := len(ds) - 1; i >= 0; i-- {
+		if blocks[i] == nil {
+			continue
+		}
+		f := ds[i].Fence
+		lines = slices.Concat(lines[:f.Start], blocks[i], lines[f.End-1:])
+	}
+	return strings.Join(lines, "\n"), problems
+}
@@ -0,0 +1,213 @@
+package snippet
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const src = `package x
+
+// F does nothing.
+func F() {
+	if true {
+		return
+	}
+}
+
+type T[K comparable] struct{}
+
+func (t *T[K]) M() int { return 1 }
+
+func (T[K]) N() {}
+
+const (
+	A = 1
+	B = 2
+)
+
+var v, w int
+
+/* func F2() {} */
+`
+
+func TestExtract(t *testing.T) {
+	for _, test := range []struct {
+		symbol  string
+		want    []string
+		wantErr string
+	}{
+		{
+			symbol: "F",
+			want:   []string{"func F() {", "    if true {", "        return", "    }", "}"},
+		},
+		{
+			symbol: "/func F/",
+			want:   []string{"func F() {", "    if true {", "        return", "    }", "}"},
+		},
+		{
+			symbol: "T.M",
+			want:   []string{"func (t *T[K]) M() int { return 1 }"},
+		},
+		{
+			symbol: "T.N",
+			want:   []string{"func (T[K]) N() {}"},
+		},
+		{
+			symbol: "T",
+			want:   []string{"type T[K comparable] struct{}"},
+		},
+		{
+			symbol: "B",
+			want:   []string{"const (", "    A = 1", "    B = 2", ")"},
+		},
+		{
+			symbol: "w",
+			want:   []string{"var v, w int"},
+		},
+		{
+			symbol:  "M",
+			wantErr: "no declaration of M",
+		},
+		{
+			symbol:  "F2",
+			wantErr: "no declaration of F2",
+		},
+		{
+			symbol:  "/^func/",
+			wantErr: "/^func/ matches 3 declarations, at lines [4 12 14]",
+		},
+		{
+			symbol:  "/(/",
+			wantErr: "bad symbol /(/",
+		},
+	} {
+		got, err := Extract([]byte(src), test.symbol)
+		switch {
+		case err == nil && test.wantErr != "":
+			t.Errorf("Extract(_,%q) = _,nil, want error with %q", test.symbol, test.wantErr)
+		case err != nil && test.wantErr == "":
+			t.Errorf("Extract(_,%q) = _,%q, want nil error", test.symbol, err.Error())
+		case err != nil && !strings.Contains(err.Error(), test.wantErr):
+			t.Errorf("Extract(_,%q) = _,%q, want error with %q", test.symbol, err.Error(), test.wantErr)
+		case !reflect.DeepEqual(got, test.want):
+			t.Errorf("Extract(_,%q) = %q, want %q", test.symbol, got, test.want)
+		}
+	}
+	if _, err := Extract([]byte("package"), "F"); err == nil {
+		t.Errorf("Extract(unparsable) = _,nil, want error")
+	}
+}
+
+func TestDirectives(t *testing.T) {
+	content := strings.Join([]string{
+		"# Doc",                        // 1
+		"<!-- embed: x.go F -->",       // 2
+		"",                             // 3
+		"```go",                        // 4
+		"old",                          // 5
+		"```",                          // 6
+		"<!--embed: /x.go /func F/-->", // 7
+		"text",                         // 8
+		"~~~",                          // 9
+		"<!-- embed: x.go F -->",       // 10, in a fence
+		"~~~",                          // 11
+		"Use `<!-- embed: x.go F -->`", // 12, not a directive
+	}, "\n")
+	got := Directives(content)
+	if len(got) != 2 {
+		t.Fatalf("Directives() = %+v, want 2 directives", got)
+	}
+	if d := got[0]; d.File != "x.go" || d.Symbol != "F" || d.Line != 2 || d.Fence == nil || d.Fence.Start != 4 {
+		t.Errorf("Directives()[0] = %+v, want x.go F at line 2, fence at line 4", d)
+	}
+	if d := got[1]; d.File != "/x.go" || d.Symbol != "/func F/" || d.Line != 7 || d.Fence != nil {
+		t.Errorf("Directives()[1] = %+v, want /x.go /func F/ at line 7, no fence", d)
+	}
+}
+
+func TestPath(t *testing.T) {
+	for _, test := range []struct {
+		file string
+		doc  string
+		want string
+	}{
+		{file: "x.go", doc: "README.md", want: "x.go"},
+		{file: "x.go", doc: "docs/a.md", want: "docs/x.go"},
+		{file: "../x.go", doc: "docs/a.md", want: "x.go"},
+		{file: "/y/x.go", doc: "docs/a.md", want: "y/x.go"},
+	} {
+		if got := (Directive{File: test.file}).Path(test.doc); got != test.want {
+			t.Errorf("Directive{File: %q}.Path(%q) = %q, want %q", test.file, test.doc, got, test.want)
+		}
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "x.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc := filepath.Join(dir, "README.md")
+	content := strings.Join([]string{
+		"<!-- embed: x.go T.M -->",
+		"```go",
+		"stale",
+		"lines",
+		"```",
+		"<!-- embed: x.go nope -->",
+		"```go",
+		"kept",
+		"```",
+		"- item",
+		"  <!-- embed: x.go T -->",
+		"  ```go",
+		"  ```",
+		"<!-- embed: missing.go T -->",
+		"```go",
+		"```",
+		"<!-- embed: x.go T -->",
+		"no block",
+		"<!-- embed: x.go T -->",
+		"```go",
+	}, "\n")
+	want := strings.Join([]string{
+		"<!-- embed: x.go T.M -->",
+		"```go",
+		"func (t *T[K]) M() int { return 1 }",
+		"```",
+		"<!-- embed: x.go nope -->",
+		"```go",
+		"kept",
+		"```",
+		"- item",
+		"  <!-- embed: x.go T -->",
+		"  ```go",
+		"  type T[K comparable] struct{}",
+		"  ```",
+		"<!-- embed: missing.go T -->",
+		"```go",
+		"```",
+		"<!-- embed: x.go T -->",
+		"no block",
+		"<!-- embed: x.go T -->",
+		"```go",
+	}, "\n")
+	got, problems := Refresh(doc, content)
+	if got != want {
+		t.Errorf("Refresh() = %q, want %q", got, want)
+	}
+	wantLines := []int{6, 14, 17, 19}
+	var gotLines []int
+	for _, p := range problems {
+		gotLines = append(gotLines, p.Line)
+	}
+	if !reflect.DeepEqual(gotLines, wantLines) {
+		t.Errorf("Refresh() problems = %+v, want problems at lines %v", problems, wantLines)
+	}
+	if again, _ := Refresh(doc, got); again != got {
+		t.Errorf("Refresh(Refresh()) = %q, want %q", again, got)
+	}
+}
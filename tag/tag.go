@@ -1,5 +1,6 @@
-// Package tag represents a git tag in the form v12.34.56. It is parsed into 3 numbers to make tags
-// comparable.
+// Package tag represents a git tag following Semantic Versioning 2.0 (https://semver.org), e.g.
+// v12.34.56, v1.2.0-rc.1 or v1.2.0+build5. It is parsed into 3 numbers and the optional
+// prerelease and build metadata to make tags comparable.
 package tag
 
 import (
@@ -10,14 +11,24 @@ import (
 )
 
 const (
-	TagFormat    = `v\d+\.\d+\.\d+` // ex. v1.23.45
-	anonymousTag = "$TAG"           // placeholder
+	// TagFormat matches a SemVer tag, ex. v1.23.45, v1.2.0-rc.1, v1.2.0-beta+exp.sha.5114f85
+	TagFormat = `v(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?` +
+		`(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?`
+	anonymousTag = "$TAG" // placeholder
+	rcPrefix     = "rc."  // first identifiers of a release candidate
 )
 
-var TagRe = regexp.MustCompile(TagFormat)
+var (
+	TagRe   = regexp.MustCompile(TagFormat)
+	numRe   = regexp.MustCompile(`^\d+$`)
+	identRe = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+)
 
 type Tag struct {
 	Major, Minor, Detail int
+	Prerelease           string // optional, ex. "rc.1"
+	Build                string // optional, ex. "build5", ignored in comparisons
 }
 
 func New(s string) (*Tag, error) {
@@ -25,27 +36,67 @@ func New(s string) (*Tag, error) {
 	if !strings.HasPrefix(s, "v") {
 		return tg, fmt.Errorf("tag %q doesn't start with 'v'", s)
 	}
-	parts := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	rest := strings.TrimPrefix(s, "v")
+	if i := strings.Index(rest, "+"); i >= 0 {
+		tg.Build = rest[i+1:]
+		rest = rest[:i]
+		if err := checkIdentifiers(tg.Build, false); err != nil {
+			return tg, fmt.Errorf("tag %q has invalid build metadata: %v", s, err)
+		}
+	}
+	if i := strings.Index(rest, "-"); i >= 0 {
+		tg.Prerelease = rest[i+1:]
+		rest = rest[:i]
+		if err := checkIdentifiers(tg.Prerelease, true); err != nil {
+			return tg, fmt.Errorf("tag %q has invalid prerelease: %v", s, err)
+		}
+	}
+	parts := strings.Split(rest, ".")
 	if len(parts) != 3 {
 		return tg, fmt.Errorf("tag %q doesn't have 3 parts, just %v", s, parts)
 	}
 	var err error
 
-	tg.Major, err = strconv.Atoi(parts[0])
+	tg.Major, err = parseNumber(parts[0])
 	if err != nil {
 		return tg, fmt.Errorf("can't parse major number of tag %q (%v)", s, err)
 	}
-	tg.Minor, err = strconv.Atoi(parts[1])
+	tg.Minor, err = parseNumber(parts[1])
 	if err != nil {
 		return tg, fmt.Errorf("can't parse minor number of tag %q (%v)", s, err)
 	}
-	tg.Detail, err = strconv.Atoi(parts[2])
+	tg.Detail, err = parseNumber(parts[2])
 	if err != nil {
 		return tg, fmt.Errorf("can't parse detail number of tag %q (%v)", s, err)
 	}
 	return tg, nil
 }
 
+// parseNumber parses a numeric part of a version, which may not have leading zeroes.
+func parseNumber(s string) (int, error) {
+	if !numRe.MatchString(s) {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("%q has a leading zero", s)
+	}
+	return strconv.Atoi(s)
+}
+
+// checkIdentifiers validates dot-separated prerelease or build identifiers. Numeric prerelease
+// identifiers may not have leading zeroes.
+func checkIdentifiers(s string, prerelease bool) error {
+	for _, id := range strings.Split(s, ".") {
+		if !identRe.MatchString(id) {
+			return fmt.Errorf("identifier %q must be non-empty and contain only [0-9A-Za-z-]", id)
+		}
+		if prerelease && numRe.MatchString(id) && len(id) > 1 && id[0] == '0' {
+			return fmt.Errorf("numeric identifier %q has a leading zero", id)
+		}
+	}
+	return nil
+}
+
 func NewZero() *Tag {
 	return &Tag{
 		Major:  0,
@@ -54,55 +105,162 @@ func NewZero() *Tag {
 	}
 }
 
-func (tg *Tag) Less(ot *Tag) bool {
+// compare returns -1, 0 or 1 when tg has a lower, the same or a higher precedence than ot.
+func (tg *Tag) compare(ot *Tag) int {
+	for _, p := range [][2]int{
+		{tg.Major, ot.Major},
+		{tg.Minor, ot.Minor},
+		{tg.Detail, ot.Detail},
+	} {
+		if c := compareInts(p[0], p[1]); c != 0 {
+			return c
+		}
+	}
+	return comparePrereleases(tg.Prerelease, ot.Prerelease)
+}
+
+func compareInts(a, b int) int {
 	switch {
-	case tg.Major < ot.Major:
-		return true
-	case tg.Major > ot.Major:
-		return false
-	case tg.Minor < ot.Minor:
-		return true
-	case tg.Minor > ot.Minor:
-		return false
-	case tg.Detail < ot.Detail:
-		return true
-	case tg.Detail > ot.Detail:
-		return false
+	case a < b:
+		return -1
+	case a > b:
+		return 1
 	default:
-		return false
+		return 0
 	}
 }
 
+// comparePrereleases compares prereleases by SemVer precedence: a version without prerelease is
+// higher than one with; identifiers are compared left to right, numerically when both are
+// numeric, numeric ones are lower than alphanumeric ones, and a longer set of identifiers is
+// higher when all preceding ones are equal.
+func comparePrereleases(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	aids, bids := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aids) && i < len(bids); i++ {
+		if c := compareIdentifiers(aids[i], bids[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(aids), len(bids))
+}
+
+func compareIdentifiers(a, b string) int {
+	aNum, bNum := numRe.MatchString(a), numRe.MatchString(b)
+	switch {
+	case aNum && bNum:
+		if c := compareInts(len(a), len(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func (tg *Tag) Less(ot *Tag) bool {
+	return tg.compare(ot) < 0
+}
+
+// Equal is true when both tags have the same precedence; build metadata is ignored.
 func (tg *Tag) Equal(ot *Tag) bool {
 	if ot == nil {
 		return false
 	}
-	return tg.Major == ot.Major && tg.Minor == ot.Minor && tg.Detail == ot.Detail
+	return tg.compare(ot) == 0
 }
 
 func (tg *Tag) Greater(ot *Tag) bool {
-	if tg.Less(ot) || tg.Equal(ot) {
-		return false
-	}
-	return true
+	return tg.compare(ot) > 0
+}
+
+// IsPrerelease is true for tags like v1.2.0-rc.1.
+func (tg *Tag) IsPrerelease() bool {
+	return tg.Prerelease != ""
 }
 
 func (tg *Tag) String() string {
-	return fmt.Sprintf("v%d.%d.%d", tg.Major, tg.Minor, tg.Detail)
+	s := fmt.Sprintf("v%d.%d.%d", tg.Major, tg.Minor, tg.Detail)
+	if tg.Prerelease != "" {
+		s += "-" + tg.Prerelease
+	}
+	if tg.Build != "" {
+		s += "+" + tg.Build
+	}
+	return s
 }
 
+// Next returns the next release, see NextPatch.
 func (tg *Tag) Next() *Tag {
-	nx := &Tag{
-		Major:  tg.Major,
-		Minor:  tg.Minor,
-		Detail: tg.Detail,
+	return tg.NextPatch()
+}
+
+// NextMajor returns the next major release: v1.2.3 becomes v2.0.0, and v2.0.0-rc.1 becomes v2.0.0.
+// Build metadata is dropped.
+func (tg *Tag) NextMajor() *Tag {
+	if tg.IsPrerelease() && tg.Minor == 0 && tg.Detail == 0 {
+		return tg.release()
 	}
-	nx.Detail++
+	return &Tag{Major: tg.Major + 1}
+}
+
+// NextMinor returns the next minor release: v1.2.3 becomes v1.3.0, and v1.3.0-rc.1 becomes v1.3.0.
+// Build metadata is dropped.
+func (tg *Tag) NextMinor() *Tag {
+	if tg.IsPrerelease() && tg.Detail == 0 {
+		return tg.release()
+	}
+	return &Tag{Major: tg.Major, Minor: tg.Minor + 1}
+}
+
+// NextPatch returns the next patch release: v1.2.0-rc.1 becomes v1.2.0, and v1.2.0 becomes v1.2.1.
+// Build metadata is dropped.
+func (tg *Tag) NextPatch() *Tag {
+	if tg.IsPrerelease() {
+		return tg.release()
+	}
+	return &Tag{Major: tg.Major, Minor: tg.Minor, Detail: tg.Detail + 1}
+}
+
+// NextPrerelease returns the next release candidate: v1.2.0-rc.1 becomes v1.2.0-rc.2, and
+// v1.2.3 becomes v1.2.4-rc.1. A prerelease that doesn't end in a number gets ".1" appended, so
+// v1.2.0-beta becomes v1.2.0-beta.1. Build metadata is dropped.
+func (tg *Tag) NextPrerelease() *Tag {
+	if !tg.IsPrerelease() {
+		nx := tg.NextPatch()
+		nx.Prerelease = rcPrefix + "1"
+		return nx
+	}
+	nx := tg.release()
+	ids := strings.Split(tg.Prerelease, ".")
+	last := ids[len(ids)-1]
+	if n, err := strconv.Atoi(last); err == nil && numRe.MatchString(last) {
+		ids[len(ids)-1] = strconv.Itoa(n + 1)
+	} else {
+		ids = append(ids, "1")
+	}
+	nx.Prerelease = strings.Join(ids, ".")
 	return nx
 }
 
+// release returns the tag without prerelease and build metadata.
+func (tg *Tag) release() *Tag {
+	return &Tag{Major: tg.Major, Minor: tg.Minor, Detail: tg.Detail}
+}
+
 func (tg *Tag) IsZero() bool {
-	return tg == nil || (tg.Major == 0 && tg.Minor == 0 && tg.Detail == 0)
+	return tg == nil || (tg.Major == 0 && tg.Minor == 0 && tg.Detail == 0 && tg.Prerelease == "")
 }
 
 func Next(s string) string {
@@ -25,6 +25,14 @@ func TestNext(t *testing.T) {
 			s:        "v12.34.99",
 			wantNext: "v12.34.100",
 		},
+		{
+			s:        "v1.2.0-rc.1",
+			wantNext: "v1.2.0",
+		},
+		{
+			s:        "v1.2.0+build5",
+			wantNext: "v1.2.1",
+		},
 		{
 			s:        "",
 			wantNext: "$TAG",
@@ -36,6 +44,207 @@ func TestNext(t *testing.T) {
 	}
 }
 
+func TestNextVariants(t *testing.T) {
+	for _, test := range []struct {
+		s              string
+		wantMajor      string
+		wantMinor      string
+		wantPatch      string
+		wantPrerelease string
+	}{
+		{
+			s:              "v1.2.3",
+			wantMajor:      "v2.0.0",
+			wantMinor:      "v1.3.0",
+			wantPatch:      "v1.2.4",
+			wantPrerelease: "v1.2.4-rc.1",
+		},
+		{
+			s:              "v1.2.3+build5",
+			wantMajor:      "v2.0.0",
+			wantMinor:      "v1.3.0",
+			wantPatch:      "v1.2.4",
+			wantPrerelease: "v1.2.4-rc.1",
+		},
+		{
+			s:              "v2.0.0-rc.1",
+			wantMajor:      "v2.0.0",
+			wantMinor:      "v2.0.0",
+			wantPatch:      "v2.0.0",
+			wantPrerelease: "v2.0.0-rc.2",
+		},
+		{
+			s:              "v1.3.0-rc.9",
+			wantMajor:      "v2.0.0",
+			wantMinor:      "v1.3.0",
+			wantPatch:      "v1.3.0",
+			wantPrerelease: "v1.3.0-rc.10",
+		},
+		{
+			s:              "v1.2.4-beta",
+			wantMajor:      "v2.0.0",
+			wantMinor:      "v1.3.0",
+			wantPatch:      "v1.2.4",
+			wantPrerelease: "v1.2.4-beta.1",
+		},
+	} {
+		tg, err := New(test.s)
+		if err != nil {
+			t.Fatalf("New(%q) = _,%v", test.s, err)
+		}
+		for _, c := range []struct {
+			name string
+			got  *Tag
+			want string
+		}{
+			{name: "NextMajor", got: tg.NextMajor(), want: test.wantMajor},
+			{name: "NextMinor", got: tg.NextMinor(), want: test.wantMinor},
+			{name: "NextPatch", got: tg.NextPatch(), want: test.wantPatch},
+			{name: "NextPrerelease", got: tg.NextPrerelease(), want: test.wantPrerelease},
+		} {
+			if c.got.String() != c.want {
+				t.Errorf("%v .%v() = %v, want %v", tg, c.name, c.got, c.want)
+			}
+			if !c.got.Greater(tg) {
+				t.Errorf("%v .%v() = %v, which isn't greater", tg, c.name, c.got)
+			}
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, test := range []struct {
+		s       string
+		wantTag *Tag
+		wantErr bool
+	}{
+		{
+			s:       "v1.2.3",
+			wantTag: &Tag{Major: 1, Minor: 2, Detail: 3},
+		},
+		{
+			s:       "v1.2.0-rc.1",
+			wantTag: &Tag{Major: 1, Minor: 2, Detail: 0, Prerelease: "rc.1"},
+		},
+		{
+			s:       "v1.2.0+build5",
+			wantTag: &Tag{Major: 1, Minor: 2, Detail: 0, Build: "build5"},
+		},
+		{
+			s:       "v1.0.0-beta-2.x+exp.sha.5114f85",
+			wantTag: &Tag{Major: 1, Minor: 0, Detail: 0, Prerelease: "beta-2.x", Build: "exp.sha.5114f85"},
+		},
+		{
+			s:       "1.2.3",
+			wantErr: true,
+		},
+		{
+			s:       "v1.2",
+			wantErr: true,
+		},
+		{
+			s:       "v01.2.3",
+			wantErr: true,
+		},
+		{
+			s:       "v1.2.3-",
+			wantErr: true,
+		},
+		{
+			s:       "v1.2.3-rc..1",
+			wantErr: true,
+		},
+		{
+			s:       "v1.2.3-rc.01",
+			wantErr: true,
+		},
+		{
+			s:       "v1.2.3+build_5",
+			wantErr: true,
+		},
+	} {
+		tg, err := New(test.s)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("New(%q) = _,%v, want error: %v", test.s, err, test.wantErr)
+			continue
+		}
+		if test.wantErr {
+			continue
+		}
+		if *tg != *test.wantTag {
+			t.Errorf("New(%q) = %+v, want %+v", test.s, tg, test.wantTag)
+		}
+		if got := tg.String(); got != test.s {
+			t.Errorf("New(%q).String() = %q, want %q", test.s, got, test.s)
+		}
+	}
+}
+
+func TestTagRe(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		want string
+	}{
+		{
+			s:    "0123abcd\trefs/tags/v1.2.3",
+			want: "v1.2.3",
+		},
+		{
+			s:    "0123abcd\trefs/tags/v1.2.0-rc.1^{}",
+			want: "v1.2.0-rc.1",
+		},
+		{
+			s:    "0123abcd\trefs/tags/v1.2.0+build5",
+			want: "v1.2.0+build5",
+		},
+		{
+			s:    "0123abcd\trefs/tags/latest",
+			want: "",
+		},
+	} {
+		if got := TagRe.FindString(test.s); got != test.want {
+			t.Errorf("TagRe.FindString(%q) = %q, want %q", test.s, got, test.want)
+		}
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	// Example from https://semver.org, in ascending order.
+	ordered := []string{
+		"v1.0.0-alpha",
+		"v1.0.0-alpha.1",
+		"v1.0.0-alpha.beta",
+		"v1.0.0-beta",
+		"v1.0.0-beta.2",
+		"v1.0.0-beta.11",
+		"v1.0.0-rc.1",
+		"v1.0.0",
+		"v1.0.1-rc.1",
+		"v2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, err := New(ordered[i])
+			if err != nil {
+				t.Fatalf("New(%q) = _,%v", ordered[i], err)
+			}
+			b, err := New(ordered[j])
+			if err != nil {
+				t.Fatalf("New(%q) = _,%v", ordered[j], err)
+			}
+			if got, want := a.Less(b), i < j; got != want {
+				t.Errorf("%v .Less(%v) = %v, want %v", a, b, got, want)
+			}
+			if got, want := a.Equal(b), i == j; got != want {
+				t.Errorf("%v .Equal(%v) = %v, want %v", a, b, got, want)
+			}
+			if got, want := a.Greater(b), i > j; got != want {
+				t.Errorf("%v .Greater(%v) = %v, want %v", a, b, got, want)
+			}
+		}
+	}
+}
+
 func TestLessGreaterEqual(t *testing.T) {
 	for _, test := range []struct {
 		tg          *Tag
@@ -93,6 +302,20 @@ func TestLessGreaterEqual(t *testing.T) {
 			wantGreater: true,
 			wantEqual:   false,
 		},
+		{
+			tg:          &Tag{Major: 1, Minor: 2, Detail: 0, Build: "build5"},
+			ot:          &Tag{Major: 1, Minor: 2, Detail: 0, Build: "build6"},
+			wantLess:    false,
+			wantGreater: false,
+			wantEqual:   true,
+		},
+		{
+			tg:          &Tag{Major: 1, Minor: 2, Detail: 0, Prerelease: "rc.1"},
+			ot:          &Tag{Major: 1, Minor: 2, Detail: 0},
+			wantLess:    true,
+			wantGreater: false,
+			wantEqual:   false,
+		},
 	} {
 		if gotLess := test.tg.Less(test.ot); gotLess != test.wantLess {
 			t.Errorf("%+v .Less(%+v): got %v, want %v", test.tg, test.ot, gotLess, test.wantLess)
@@ -133,6 +356,10 @@ func TestIsZero(t *testing.T) {
 			tg:         &Tag{Major: 0, Minor: 0, Detail: 1},
 			wantIsZero: false,
 		},
+		{
+			tg:         &Tag{Major: 0, Minor: 0, Detail: 0, Prerelease: "alpha"},
+			wantIsZero: false,
+		},
 	} {
 		if gotZero := test.tg.IsZero(); gotZero != test.wantIsZero {
 			t.Errorf("%+v .IsZero() = %v, want %v", test.tg, gotZero, test.wantIsZero)
@@ -1,4 +1,4 @@
-// Package tags compares tags in the form v1.23.45.
+// Package tags compares tags in the form v1.23.45, including prereleases like v1.23.45-rc.1.
 package tags
 
 import (
@@ -28,15 +28,28 @@ func (t *Tags) HasTags() bool {
 	return len(t.tags) > 0
 }
 
+// Highest returns the tag with the highest precedence, or a zero tag when there are no tags.
 func (t *Tags) Highest() *tag.Tag {
-	if len(t.tags) == 0 {
-		return tag.NewZero()
-	}
-	var high int
-	for i := 1; i < len(t.tags); i++ {
-		if t.tags[i].Greater(t.tags[high]) {
-			high = i
+	return t.highest(true)
+}
+
+// HighestRelease is like Highest, but skips prereleases.
+func (t *Tags) HighestRelease() *tag.Tag {
+	return t.highest(false)
+}
+
+func (t *Tags) highest(prereleases bool) *tag.Tag {
+	var high *tag.Tag
+	for _, tg := range t.tags {
+		if !prereleases && tg.IsPrerelease() {
+			continue
+		}
+		if high == nil || tg.Greater(high) {
+			high = tg
 		}
 	}
-	return t.tags[high]
+	if high == nil {
+		return tag.NewZero()
+	}
+	return high
 }
@@ -7,34 +7,64 @@ import (
 func TestAll(t *testing.T) {
 	tgs := New()
 	for _, test := range []struct {
-		s        string
-		wantErr  bool
-		wantHigh string
+		s               string
+		wantErr         bool
+		wantHigh        string
+		wantHighRelease string
 	}{
 		{
-			s:        "v1.0.0",
-			wantErr:  false,
-			wantHigh: "v1.0.0",
+			s:               "v1.0.0",
+			wantErr:         false,
+			wantHigh:        "v1.0.0",
+			wantHighRelease: "v1.0.0",
 		},
 		{
-			s:        "v1.0.1",
-			wantErr:  false,
-			wantHigh: "v1.0.1",
+			s:               "v1.0.1",
+			wantErr:         false,
+			wantHigh:        "v1.0.1",
+			wantHighRelease: "v1.0.1",
 		},
 		{
-			s:        "v1.0.2",
-			wantErr:  false,
-			wantHigh: "v1.0.2",
+			s:               "v1.0.2",
+			wantErr:         false,
+			wantHigh:        "v1.0.2",
+			wantHighRelease: "v1.0.2",
 		},
 		{
-			s:        "v1.0.10",
-			wantErr:  false,
-			wantHigh: "v1.0.10",
+			s:               "v1.0.10",
+			wantErr:         false,
+			wantHigh:        "v1.0.10",
+			wantHighRelease: "v1.0.10",
 		},
 		{
-			s:        "v1.1.2",
-			wantErr:  false,
-			wantHigh: "v1.1.2",
+			s:               "v1.1.2",
+			wantErr:         false,
+			wantHigh:        "v1.1.2",
+			wantHighRelease: "v1.1.2",
+		},
+		{
+			s:               "v1.2.0-rc.1",
+			wantErr:         false,
+			wantHigh:        "v1.2.0-rc.1",
+			wantHighRelease: "v1.1.2",
+		},
+		{
+			s:               "v1.2.0-rc.2",
+			wantErr:         false,
+			wantHigh:        "v1.2.0-rc.2",
+			wantHighRelease: "v1.1.2",
+		},
+		{
+			s:               "v1.2.0+build5",
+			wantErr:         false,
+			wantHigh:        "v1.2.0+build5",
+			wantHighRelease: "v1.2.0+build5",
+		},
+		{
+			s:               "v1.3",
+			wantErr:         true,
+			wantHigh:        "v1.2.0+build5",
+			wantHighRelease: "v1.2.0+build5",
 		},
 	} {
 		err := tgs.Add(test.s)
@@ -52,5 +82,8 @@ func TestAll(t *testing.T) {
 		if gotHigh := tgs.Highest().String(); gotHigh != test.wantHigh {
 			t.Errorf("Highest() = %q, want %q", gotHigh, test.wantHigh)
 		}
+		if gotHigh := tgs.HighestRelease().String(); gotHigh != test.wantHighRelease {
+			t.Errorf("HighestRelease() = %q, want %q", gotHigh, test.wantHighRelease)
+		}
 	}
 }
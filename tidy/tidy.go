@@ -0,0 +1,78 @@
+// Package tidy interprets the output of `go mod tidy -diff` when run offline (GOPROXY=off): the
+// diff that tidying would apply to go.mod and go.sum, and the modules that aren't in the local
+// module cache.
+package tidy
+
+import (
+	"strings"
+)
+
+const (
+	offline     = ": module lookup disabled by GOPROXY=off"
+	noPackage   = "cannot find module providing package "
+	unsupported = "flag provided but not defined: -diff"
+)
+
+// Result is the interpreted output of `go mod tidy -diff`.
+type Result struct {
+	Diff    []string // unified diff of go.mod and go.sum, empty when tidy
+	Missing []string // modules or packages that need the network, e.g. "example.com/x@v1.2.3"
+	Rest    []string // other output
+}
+
+// Parse interprets the output lines of `go mod tidy -diff`.
+func Parse(lines []string) Result {
+	var r Result
+	seen := map[string]struct{}{}
+	inDiff := false
+	for i, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "diff "):
+			inDiff = true
+		case strings.Contains(l, offline):
+			inDiff = false
+			m := missing(l)
+			if _, ok := seen[m]; !ok {
+				seen[m] = struct{}{}
+				r.Missing = append(r.Missing, m)
+			}
+			continue
+		case strings.HasSuffix(l, " imports") && i+1 < len(lines) && strings.Contains(lines[i+1], offline):
+			// "go: x imports" introduces the next line, a missing module.
+			continue
+		case progress(l):
+			continue
+		case strings.HasPrefix(l, "go: "):
+			inDiff = false
+		}
+		if inDiff {
+			r.Diff = append(r.Diff, l)
+		} else {
+			r.Rest = append(r.Rest, l)
+		}
+	}
+	return r
+}
+
+// Unsupported returns true when the go command doesn't know `go mod tidy -diff` (before Go 1.23).
+func Unsupported(lines []string) bool {
+	for _, l := range lines {
+		if strings.Contains(l, unsupported) {
+			return true
+		}
+	}
+	return false
+}
+
+// progress returns true for lines that only report progress.
+func progress(l string) bool {
+	return strings.HasPrefix(l, "go: downloading ") || strings.HasPrefix(l, "go: finding module for package ")
+}
+
+// missing returns what couldn't be looked up offline, given a line such as
+// "\tfoo/bar: example.com/x@v1.2.3: module lookup disabled by GOPROXY=off".
+func missing(l string) string {
+	l = strings.TrimPrefix(strings.TrimSpace(l[:strings.Index(l, offline)]), "go: ")
+	parts := strings.Split(l, ": ")
+	return strings.TrimPrefix(parts[len(parts)-1], noPackage)
+}
@@ -0,0 +1,98 @@
+package tidy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		lines []string
+		want  Result
+	}{
+		{
+			lines: nil,
+			want:  Result{},
+		},
+		{
+			lines: []string{
+				"diff current/go.mod tidy/go.mod",
+				"--- current/go.mod",
+				"+++ tidy/go.mod",
+				"@@ -1,5 +1,3 @@",
+				" module example.com/m",
+				" ",
+				" go 1.25",
+				"-",
+				"-require golang.org/x/mod v0.37.0",
+			},
+			want: Result{Diff: []string{
+				"diff current/go.mod tidy/go.mod",
+				"--- current/go.mod",
+				"+++ tidy/go.mod",
+				"@@ -1,5 +1,3 @@",
+				" module example.com/m",
+				" ",
+				" go 1.25",
+				"-",
+				"-require golang.org/x/mod v0.37.0",
+			}},
+		},
+		{
+			lines: []string{
+				"go: downloading github.com/nope/dep v1.2.3",
+				"go: example.com/m imports",
+				"\tgithub.com/nope/dep: module lookup disabled by GOPROXY=off",
+				"go: example.com/m imports",
+				"\tgithub.com/other/pkg/sub: github.com/nope/dep@v1.2.3: module lookup disabled by GOPROXY=off",
+				"go: finding module for package golang.org/x/mod/semver",
+				"go: example.com/m imports",
+				"\tgolang.org/x/mod/semver: cannot find module providing package golang.org/x/mod/semver: module lookup disabled by GOPROXY=off",
+				"go: github.com/x/y@v1.0.0: module lookup disabled by GOPROXY=off",
+				"go: github.com/x/y@v1.0.0: module lookup disabled by GOPROXY=off",
+			},
+			want: Result{
+				Missing: []string{
+					"github.com/nope/dep",
+					"github.com/nope/dep@v1.2.3",
+					"golang.org/x/mod/semver",
+					"github.com/x/y@v1.0.0",
+				},
+			},
+		},
+		{
+			lines: []string{
+				"go: example.com/m imports",
+				"\texample.com/bad: malformed module path",
+			},
+			want: Result{Rest: []string{
+				"go: example.com/m imports",
+				"\texample.com/bad: malformed module path",
+			}},
+		},
+	} {
+		if got := Parse(test.lines); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", test.lines, got, test.want)
+		}
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	for _, test := range []struct {
+		lines []string
+		want  bool
+	}{
+		{
+			lines: []string{"flag provided but not defined: -diff", "usage: go mod tidy [-e] [-v]"},
+			want:  true,
+		},
+		{
+			lines: []string{"diff current/go.mod tidy/go.mod"},
+			want:  false,
+		},
+	} {
+		if got := Unsupported(test.lines); got != test.want {
+			t.Errorf("Unsupported(%q) = %v, want %v", test.lines, got, test.want)
+		}
+	}
+}